@@ -34,11 +34,29 @@ func (api *API) OpenURI(uri string) *error {
 	return nil
 }
 
+// OpenSearchResult opens a resource found by a search, recording it in the
+// search history
+func (api *API) OpenSearchResult(searchID string, resourceID string, uri string) *error {
+	if len(searchID) > 0 {
+		request := &protocol.OpenSearchResultRequest{
+			SearchId:   searchID,
+			ResourceId: resourceID,
+		}
+
+		if _, err := api.Client.OpenSearchResult(_context.TODO(), request); err != nil {
+			return &err
+		}
+	}
+
+	return api.OpenURI(uri)
+}
+
 func (api *API) Execute(command string) (response *protocol.ExecuteResponse) {
 	var err error
 
 	request := &protocol.ExecuteRequest{
-		Command: command,
+		ContextId: api.Config.ReplContextID,
+		Command:   command,
 	}
 
 	if response, err = api.Client.Execute(_context.TODO(), request); err != nil {
@@ -50,12 +68,31 @@ func (api *API) Execute(command string) (response *protocol.ExecuteResponse) {
 	return
 }
 
+func (api *API) ListCommands() (response *protocol.ListCommandsResponse) {
+	var err error
+
+	if response, err = api.Client.ListCommands(_context.TODO(), &protocol.ListCommandsRequest{}); err != nil {
+		response = &protocol.ListCommandsResponse{
+			Error: engine.NewProtocolError(engine.ErrUnknown, err),
+		}
+	}
+
+	return
+}
+
 func (api *API) Query(value string) (response *protocol.QueryResponse) {
+	return api.Search(&protocol.QueryRequest{
+		Value: value,
+	})
+}
+
+// Search runs a query with paging, sorting, facets and filters, in the current
+// context unless the request names another one
+func (api *API) Search(request *protocol.QueryRequest) (response *protocol.QueryResponse) {
 	var err error
 
-	request := &protocol.QueryRequest{
-		Value:     value,
-		ContextId: api.Config.ReplContextID,
+	if request.ContextId == "" {
+		request.ContextId = api.Config.ReplContextID
 	}
 
 	if response, err = api.Client.Query(_context.TODO(), request); err != nil {
@@ -67,6 +104,112 @@ func (api *API) Query(value string) (response *protocol.QueryResponse) {
 	return
 }
 
+// Suggest completes the query text typed so far, in the current context
+func (api *API) Suggest(value string) (response *protocol.SuggestResponse) {
+	var err error
+
+	request := &protocol.SuggestRequest{
+		ContextId: api.Config.ReplContextID,
+		Value:     value,
+	}
+
+	if response, err = api.Client.Suggest(_context.TODO(), request); err != nil {
+		response = &protocol.SuggestResponse{
+			Error: engine.NewProtocolError(engine.ErrUnknown, err),
+		}
+	}
+
+	return
+}
+
+// SaveQuery stores a named query string of the current context, to run it
+// again and be notified of its new matches
+func (api *API) SaveQuery(name string, value string) (response *protocol.SaveQueryResponse) {
+	var err error
+
+	request := &protocol.SaveQueryRequest{
+		ContextId: api.Config.ReplContextID,
+		Name:      name,
+		Value:     value,
+	}
+
+	if response, err = api.Client.SaveQuery(_context.TODO(), request); err != nil {
+		response = &protocol.SaveQueryResponse{
+			Error: engine.NewProtocolError(engine.ErrUnknown, err),
+		}
+	}
+
+	return
+}
+
+func (api *API) ListSavedQueries() (response *protocol.ListSavedQueriesResponse) {
+	var err error
+
+	request := &protocol.ListSavedQueriesRequest{
+		ContextId: api.Config.ReplContextID,
+	}
+
+	if response, err = api.Client.ListSavedQueries(_context.TODO(), request); err != nil {
+		response = &protocol.ListSavedQueriesResponse{
+			Error: engine.NewProtocolError(engine.ErrUnknown, err),
+		}
+	}
+
+	return
+}
+
+func (api *API) RunSavedQuery(savedQueryIDOrName string) (response *protocol.RunSavedQueryResponse) {
+	var err error
+
+	request := &protocol.RunSavedQueryRequest{
+		ContextId: api.Config.ReplContextID,
+		Id:        savedQueryIDOrName,
+	}
+
+	if response, err = api.Client.RunSavedQuery(_context.TODO(), request); err != nil {
+		response = &protocol.RunSavedQueryResponse{
+			Error: engine.NewProtocolError(engine.ErrUnknown, err),
+		}
+	}
+
+	return
+}
+
+// GetSearchHistory lists the recent searches of the current context, or only
+// those that found nothing
+func (api *API) GetSearchHistory(zeroHits bool) (response *protocol.GetSearchHistoryResponse) {
+	var err error
+
+	request := &protocol.GetSearchHistoryRequest{
+		ContextId: api.Config.ReplContextID,
+		ZeroHits:  zeroHits,
+	}
+
+	if response, err = api.Client.GetSearchHistory(_context.TODO(), request); err != nil {
+		response = &protocol.GetSearchHistoryResponse{
+			Error: engine.NewProtocolError(engine.ErrUnknown, err),
+		}
+	}
+
+	return
+}
+
+func (api *API) ClearSearchHistory() (response *protocol.ClearSearchHistoryResponse) {
+	var err error
+
+	request := &protocol.ClearSearchHistoryRequest{
+		ContextId: api.Config.ReplContextID,
+	}
+
+	if response, err = api.Client.ClearSearchHistory(_context.TODO(), request); err != nil {
+		response = &protocol.ClearSearchHistoryResponse{
+			Error: engine.NewProtocolError(engine.ErrUnknown, err),
+		}
+	}
+
+	return
+}
+
 func (api *API) IndexURI(uri string) (response *protocol.IndexURIResponse) {
 	var err error
 
@@ -84,6 +227,73 @@ func (api *API) IndexURI(uri string) (response *protocol.IndexURIResponse) {
 	return
 }
 
+func (api *API) DeleteSource(source string) (response *protocol.DeleteSourceResponse) {
+	var err error
+
+	request := &protocol.DeleteSourceRequest{
+		ContextId: api.Config.ReplContextID,
+		Source:    source,
+	}
+
+	if response, err = api.Client.DeleteSource(_context.TODO(), request); err != nil {
+		response = &protocol.DeleteSourceResponse{
+			Error: engine.NewProtocolError(engine.ErrUnknown, err),
+		}
+	}
+
+	return
+}
+
+func (api *API) GetJob(jobID string) (response *protocol.GetJobResponse) {
+	var err error
+
+	request := &protocol.GetJobRequest{
+		Id: jobID,
+	}
+
+	if response, err = api.Client.GetJob(_context.TODO(), request); err != nil {
+		response = &protocol.GetJobResponse{
+			Error: engine.NewProtocolError(engine.ErrUnknown, err),
+		}
+	}
+
+	return
+}
+
+func (api *API) GetJobs() (response *protocol.GetJobsResponse) {
+	var err error
+
+	request := &protocol.GetJobsRequest{}
+
+	if len(api.Config.ReplContextID) > 0 {
+		request.ContextId = &api.Config.ReplContextID
+	}
+
+	if response, err = api.Client.GetJobs(_context.TODO(), request); err != nil {
+		response = &protocol.GetJobsResponse{
+			Error: engine.NewProtocolError(engine.ErrUnknown, err),
+		}
+	}
+
+	return
+}
+
+func (api *API) CancelJob(jobID string) (response *protocol.CancelJobResponse) {
+	var err error
+
+	request := &protocol.CancelJobRequest{
+		Id: jobID,
+	}
+
+	if response, err = api.Client.CancelJob(_context.TODO(), request); err != nil {
+		response = &protocol.CancelJobResponse{
+			Error: engine.NewProtocolError(engine.ErrUnknown, err),
+		}
+	}
+
+	return
+}
+
 func (api *API) GetContexts() (response *protocol.GetContextsResponse) {
 	var err error
 
@@ -98,6 +308,41 @@ func (api *API) GetContexts() (response *protocol.GetContextsResponse) {
 	return
 }
 
+func (api *API) GetSource(sourceID string) (response *protocol.GetSourceResponse) {
+	var err error
+
+	request := &protocol.GetSourceRequest{
+		ContextId: api.Config.ReplContextID,
+		Id:        sourceID,
+	}
+
+	if response, err = api.Client.GetSource(_context.TODO(), request); err != nil {
+		response = &protocol.GetSourceResponse{
+			Error: engine.NewProtocolError(engine.ErrUnknown, err),
+		}
+	}
+
+	return
+}
+
+func (api *API) SetSourceWeight(sourceID string, weight float64) (response *protocol.UpdateSourceResponse) {
+	var err error
+
+	request := &protocol.UpdateSourceRequest{
+		ContextId: api.Config.ReplContextID,
+		Id:        sourceID,
+		Weight:    &weight,
+	}
+
+	if response, err = api.Client.UpdateSource(_context.TODO(), request); err != nil {
+		response = &protocol.UpdateSourceResponse{
+			Error: engine.NewProtocolError(engine.ErrUnknown, err),
+		}
+	}
+
+	return
+}
+
 func (api *API) GetSources() (response *protocol.GetSourcesResponse) {
 	var err error
 
@@ -128,6 +373,23 @@ func (api *API) GetResources() (response *protocol.GetResourcesResponse) {
 	return
 }
 
+func (api *API) GetRelatedResources(resourceID string) (response *protocol.GetRelatedResourcesResponse) {
+	var err error
+
+	request := &protocol.GetRelatedResourcesRequest{
+		ContextId: api.Config.ReplContextID,
+		Id:        resourceID,
+	}
+
+	if response, err = api.Client.GetRelatedResources(_context.TODO(), request); err != nil {
+		response = &protocol.GetRelatedResourcesResponse{
+			Error: engine.NewProtocolError(engine.ErrUnknown, err),
+		}
+	}
+
+	return
+}
+
 func (api *API) CreateContext(name string) (response *protocol.CreateContextResponse) {
 	var err error
 
@@ -142,6 +404,62 @@ func (api *API) CreateContext(name string) (response *protocol.CreateContextResp
 	return
 }
 
+// UpdateContext renames the context when name is set, and makes it the
+// default context or not when isDefault is set
+func (api *API) UpdateContext(contextID string, name string, isDefault *bool) (response *protocol.UpdateContextResponse) {
+	var err error
+
+	request := &protocol.UpdateContextRequest{
+		ContextId: contextID,
+		IsDefault: isDefault,
+	}
+
+	if len(name) > 0 {
+		request.Name = &name
+	}
+
+	if response, err = api.Client.UpdateContext(_context.TODO(), request); err != nil {
+		response = &protocol.UpdateContextResponse{
+			Error: engine.NewProtocolError(engine.ErrUnknown, err),
+		}
+	}
+
+	return
+}
+
+func (api *API) DeleteContext(contextID string) (response *protocol.DeleteContextResponse) {
+	var err error
+
+	request := &protocol.DeleteContextRequest{
+		ContextId: contextID,
+	}
+
+	if response, err = api.Client.DeleteContext(_context.TODO(), request); err != nil {
+		response = &protocol.DeleteContextResponse{
+			Error: engine.NewProtocolError(engine.ErrUnknown, err),
+		}
+	}
+
+	return
+}
+
+func (api *API) CloneContext(contextID string, name string) (response *protocol.CloneContextResponse) {
+	var err error
+
+	request := &protocol.CloneContextRequest{
+		ContextId: contextID,
+		Name:      name,
+	}
+
+	if response, err = api.Client.CloneContext(_context.TODO(), request); err != nil {
+		response = &protocol.CloneContextResponse{
+			Error: engine.NewProtocolError(engine.ErrUnknown, err),
+		}
+	}
+
+	return
+}
+
 func (api *API) ExportContexts(contextIds []string) (response *protocol.ExportContextsResponse) {
 	var err error
 
@@ -184,3 +502,44 @@ func (api *API) ExportContexts(contextIds []string) (response *protocol.ExportCo
 
 	return
 }
+
+func (api *API) ImportContexts() (response *protocol.ImportContextsResponse) {
+	var err error
+
+	request := &protocol.ImportContextsRequest{
+		ContextId: api.Config.ReplContextID,
+	}
+
+	if request.InputPath, err = runtime.OpenFileDialog(api.Runtime.Context(), runtime.OpenDialogOptions{
+		Title: "Import context(s)",
+		Filters: []runtime.FileFilter{{
+			DisplayName: "YAML Files (*.yaml, *.yml)",
+			Pattern:     "*.yaml;*.yml",
+		}},
+		ShowHiddenFiles: false,
+	}); err != nil {
+		response = &protocol.ImportContextsResponse{
+			Error: engine.NewProtocolError(engine.ErrUnknown, err),
+		}
+		return
+	}
+
+	if len(request.InputPath) < 1 {
+		response = &protocol.ImportContextsResponse{
+			Error: engine.NewProtocolError(),
+		}
+		return
+	}
+
+	fmt.Printf("Importing contexts: %+v\n", request)
+
+	if response, err = api.Client.ImportContexts(_context.TODO(), request); err != nil {
+		response = &protocol.ImportContextsResponse{
+			Error: engine.NewProtocolError(engine.ErrUnknown, err),
+		}
+	}
+
+	fmt.Printf("Response: %+v\n", response)
+
+	return
+}
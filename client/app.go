@@ -3,10 +3,13 @@ package client
 import (
 	_context "context"
 	"fmt"
+	"io"
 	"io/fs"
 	_runtime "runtime"
+	"strings"
 
 	"risp/config"
+	"risp/engine"
 	"risp/protocol"
 
 	"github.com/wailsapp/wails/v2"
@@ -87,6 +90,8 @@ func (app *App) Run() error {
 
 func (app *App) OnStartup(context _context.Context) {
 	app.context = context
+
+	go app.forwardEvents()
 }
 
 func (app *App) OnShutdown(context _context.Context) {
@@ -99,6 +104,31 @@ func (app *App) OnBeforeClose(context _context.Context) (preventClose bool) {
 	return
 }
 
+func (app *App) forwardEvents() {
+	var (
+		err    error
+		stream protocol.Risp_SubscribeClient
+		event  *protocol.Event
+	)
+
+	if stream, err = app.Client.Subscribe(app.context, &protocol.SubscribeRequest{}); err != nil {
+		fmt.Printf("error: %+v\n", err)
+		return
+	}
+
+	for {
+		if event, err = stream.Recv(); err != nil {
+			if err != io.EOF {
+				fmt.Printf("error: %+v\n", err)
+			}
+
+			return
+		}
+
+		runtime.EventsEmit(app.context, fmt.Sprintf("risp:%s", strings.ToLower(event.Type.String())), event)
+	}
+}
+
 func (app *App) Menu() (appMenu *menu.Menu) {
 	appMenu = menu.NewMenu()
 
@@ -128,13 +158,11 @@ func (app *App) handleQuit(callbackData *menu.CallbackData) {
 }
 
 func (app *App) handleImportContexts(callbackData *menu.CallbackData) {
-	filePath, err := runtime.OpenFileDialog(app.context, runtime.OpenDialogOptions{})
+	response := app.ImportContexts()
 
-	if err != nil {
-		fmt.Printf("error: %+v\n", err)
+	if response.Error != nil && response.Error.Code != engine.ErrAllGood {
+		fmt.Printf("error: %+v\n", response.Error)
 	}
-
-	fmt.Printf("selected path: %+v\n", filePath)
 }
 
 func (app *App) handleOpenFile(callbackData *menu.CallbackData) {
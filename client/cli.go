@@ -5,14 +5,20 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
 
 	"risp/config"
+	"risp/engine"
 	"risp/protocol"
 )
 
 type CLI struct {
 	*API
+	didYouMean *protocol.QueryRequest
 }
 
 func NewCLI(config *config.Config, client protocol.RispClient) (cli *CLI) {
@@ -43,15 +49,19 @@ func (cli *CLI) Run() (err error) {
 		// 	fmt.Printf("\033[H\033[2J%s%s", config.ReplPrompt, buffer)
 		case '\n':
 			line := string(buffer)
+			didYouMean := cli.didYouMean
+			cli.didYouMean = nil
 
-			if strings.HasPrefix(line, "query ") {
-				response := cli.Query(line[6:])
-
-				fmt.Printf("response: %+v\n", response)
-			} else {
-				response := cli.Execute(line)
-
-				fmt.Printf("response: %+v\n", response)
+			if didYouMean != nil && strings.TrimSpace(line) == "y" {
+				cli.printQueryResponse(didYouMean, cli.Search(didYouMean))
+			} else if strings.HasPrefix(line, "query ") {
+				if request, err := parseQueryLine(line[6:]); err != nil {
+					fmt.Printf("error: %s\n", err)
+				} else {
+					cli.printQueryResponse(request, cli.Search(request))
+				}
+			} else if strings.TrimSpace(line) != "" {
+				cli.printExecuteResponse(cli.Execute(line))
 			}
 
 			buffer = make([]byte, 0)
@@ -61,3 +71,181 @@ func (cli *CLI) Run() (err error) {
 		}
 	}
 }
+
+func (cli *CLI) printExecuteResponse(response *protocol.ExecuteResponse) {
+	if response.Error.GetCode() != engine.ErrAllGood {
+		fmt.Printf("error: %s\n", response.Error.GetMessage())
+		return
+	}
+
+	if response.Command == "context use" && response.Context != nil {
+		cli.Config.ReplContextID = response.Context.Id
+	}
+
+	for _, line := range response.Output {
+		fmt.Println(line)
+	}
+}
+
+// parseQueryLine reads the "--facets", "--filter <field>=<value>", "--all",
+// "--contexts <id,...>", "--recent <half life>" and "--explain" flags leading
+// a query line, the rest of the line being the query itself
+func parseQueryLine(line string) (request *protocol.QueryRequest, err error) {
+	request = &protocol.QueryRequest{}
+
+	for {
+		line = strings.TrimLeft(line, " ")
+
+		words := strings.SplitN(line, " ", 2)
+		if !strings.HasPrefix(words[0], "--") || len(words) < 2 {
+			break
+		}
+
+		switch words[0] {
+		case "--facets":
+			for field := range protocol.QueryFacetField_name {
+				request.Facets = append(request.Facets, &protocol.QueryFacetRequest{
+					Field: protocol.QueryFacetField(field),
+				})
+			}
+
+			line = words[1]
+		case "--all":
+			request.AllContexts = true
+
+			line = words[1]
+		case "--explain":
+			request.Explain = true
+
+			line = words[1]
+		case "--contexts":
+			words = strings.SplitN(strings.TrimLeft(words[1], " "), " ", 2)
+
+			request.ContextIds = append(request.ContextIds, strings.Split(words[0], ",")...)
+
+			line = ""
+			if len(words) > 1 {
+				line = words[1]
+			}
+		case "--filter":
+			words = strings.SplitN(strings.TrimLeft(words[1], " "), " ", 2)
+
+			filter := strings.SplitN(words[0], "=", 2)
+			if len(filter) < 2 {
+				return nil, fmt.Errorf("invalid filter '%s', expected <field>=<value>", words[0])
+			}
+
+			field, isFacetField := protocol.QueryFacetField_value[strings.ToUpper(filter[0])]
+			if !isFacetField {
+				return nil, fmt.Errorf("invalid filter field '%s'", filter[0])
+			}
+
+			request.Filters = append(request.Filters, &protocol.QueryFilter{
+				Field:  protocol.QueryFacetField(field),
+				Values: []string{filter[1]},
+			})
+
+			line = ""
+			if len(words) > 1 {
+				line = words[1]
+			}
+		case "--recent":
+			var halfLife time.Duration
+
+			words = strings.SplitN(strings.TrimLeft(words[1], " "), " ", 2)
+
+			if halfLife, err = parseHalfLife(words[0]); err != nil {
+				return nil, err
+			}
+
+			request.Recency = &protocol.QueryRecency{
+				HalfLife: halfLife.Milliseconds(),
+			}
+
+			line = ""
+			if len(words) > 1 {
+				line = words[1]
+			}
+		default:
+			return nil, fmt.Errorf("invalid query flag '%s'", words[0])
+		}
+	}
+
+	request.Value = line
+	return
+}
+
+// parseHalfLife reads a duration of days, weeks or years ("30d", "2w", "1y"),
+// or any duration time.ParseDuration reads
+func parseHalfLife(value string) (halfLife time.Duration, err error) {
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+		"y": 365 * 24 * time.Hour,
+	}
+
+	if len(value) < 1 {
+		return 0, fmt.Errorf("missing half life")
+	}
+
+	if unit, hasUnit := units[value[len(value)-1:]]; hasUnit {
+		var count float64
+
+		if count, err = strconv.ParseFloat(value[:len(value)-1], 64); err == nil && count > 0 {
+			return time.Duration(count * float64(unit)), nil
+		}
+	}
+
+	if halfLife, err = time.ParseDuration(value); err != nil || halfLife <= 0 {
+		return 0, fmt.Errorf("invalid half life '%s', expected e.g. '30d', '2w' or '12h'", value)
+	}
+
+	return
+}
+
+// printQueryResponse lists the hits and facets of a query, offering to run the
+// corrected query when the response proposes one
+func (cli *CLI) printQueryResponse(request *protocol.QueryRequest, response *protocol.QueryResponse) {
+	if response.Error.GetCode() != engine.ErrAllGood {
+		fmt.Printf("error: %s\n", response.Error.GetMessage())
+		return
+	}
+
+	for _, edge := range response.Edges {
+		fmt.Printf("%8.4f  %s  %s%s\n", edge.Score, edge.ContextId, edge.Resource.GetSourceCanonicalUri(), edge.Resource.GetCanonicalUri())
+
+		if edge.Explanation != nil {
+			printQueryExplanation(edge.Explanation, 1)
+		}
+	}
+
+	fmt.Printf("%d of %d hit(s) in %dms\n", len(response.Edges), response.EdgesTotal, response.Took)
+
+	for _, facet := range response.Facets {
+		buckets := make([]string, 0, len(facet.Buckets))
+
+		for _, bucket := range facet.Buckets {
+			buckets = append(buckets, fmt.Sprintf("%s (%d)", bucket.Value, bucket.Count))
+		}
+
+		fmt.Printf("%s: %s\n", strings.ToLower(facet.Field.String()), strings.Join(buckets, ", "))
+	}
+
+	if response.DidYouMean != "" {
+		didYouMean := proto.Clone(request).(*protocol.QueryRequest)
+		didYouMean.Value = response.DidYouMean
+
+		cli.didYouMean = didYouMean
+		fmt.Printf("did you mean \"%s\"? enter \"y\" to search it instead\n", response.DidYouMean)
+	}
+}
+
+// printQueryExplanation prints a score breakdown as a tree, one level of
+// indentation per level of the tree
+func printQueryExplanation(explanation *protocol.QueryExplanation, depth int) {
+	fmt.Printf("%s%8.4f  %s\n", strings.Repeat("  ", depth), explanation.Value, explanation.Message)
+
+	for _, child := range explanation.Children {
+		printQueryExplanation(child, depth+1)
+	}
+}
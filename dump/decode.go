@@ -7,7 +7,7 @@ import (
 )
 
 func DecodeConfigYAML(data []byte) (configYAML *ConfigYAML, err error) {
-	var rispYAML *RispYAML
+	rispYAML := &RispYAML{}
 
 	if err = yaml.Unmarshal(data, rispYAML); err != nil {
 		return
@@ -23,13 +23,13 @@ func DecodeConfigYAML(data []byte) (configYAML *ConfigYAML, err error) {
 }
 
 func DecodeDataYAML(data []byte) (dataYAML *DataYAML, err error) {
-	var rispYAML *RispYAML
+	rispYAML := &RispYAML{}
 
 	if err = yaml.Unmarshal(data, rispYAML); err != nil {
 		return
 	}
 
-	if rispYAML.Config == nil {
+	if rispYAML.Data == nil {
 		err = fmt.Errorf("missing data")
 		return
 	}
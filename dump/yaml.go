@@ -36,13 +36,17 @@ type DataYAML struct {
 }
 
 type ContextYAML struct {
+	ID        string        `yaml:"id,omitempty"`
 	Name      string        `yaml:"name,omitempty"`
 	IsDefault bool          `yaml:"isDefault,omitempty"`
+	Weight    float64       `yaml:"weight,omitempty"`
 	Sources   []*SourceYAML `yaml:"sources,omitempty"`
 }
 
 type SourceYAML struct {
+	ID        string    `yaml:"id,omitempty"`
 	URI       string    `yaml:"uri,omitempty"`
+	Weight    float64   `yaml:"weight,omitempty"`
 	Resources Resources `yaml:"resources,omitempty"`
 }
 
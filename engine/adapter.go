@@ -19,7 +19,7 @@ type Adapter interface {
 	UnmarshalMap(value map[string]interface{}) error
 	UnmarshalDBDocument(document *clover.Document) error
 
-	Index() error
+	Index(job *Job) error
 }
 
 type AdapterData interface {
@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"net/url"
@@ -90,16 +91,21 @@ func (adapterDataFS *AdapterDataFS) UnmarshalDBDocument(document *clover.Documen
 
 type AdapterFS struct {
 	Adapter
-	source   *Source
-	database *clover.DB
-	index    bleve.Index
+	source     *Source
+	database   *clover.DB
+	index      bleve.Index
+	events     *EventBus
+	job        *Job
+	crawledIDs map[string]bool // the resources of the files found by the crawl
+	isPartial  bool            // some files could not be crawled
 }
 
-func NewAdapterFS(source *Source, database *clover.DB, index bleve.Index) *AdapterFS {
+func NewAdapterFS(source *Source, database *clover.DB, index bleve.Index, events *EventBus) *AdapterFS {
 	return &AdapterFS{
 		source:   source,
 		database: database,
 		index:    index,
+		events:   events,
 	}
 }
 
@@ -123,13 +129,15 @@ func (adapterFS *AdapterFS) UnmarshalDBDocument(document *clover.Document) error
 	return adapterFS.source.AdapterData.UnmarshalDBDocument(document)
 }
 
-func (adapterFS *AdapterFS) Index() (err error) {
+func (adapterFS *AdapterFS) Index(job *Job) (err error) {
 	var (
-		parsedURI *url.URL
-		documents []*clover.Document
-		pathStat  fs.FileInfo
+		parsedURI  *url.URL
+		document   *clover.Document
+		isInserted bool
 	)
 
+	adapterFS.job = job
+
 	if parsedURI, err = url.Parse(adapterFS.source.CanonicalURI); err != nil {
 		return fmt.Errorf("invalid URI '%s': %+v", adapterFS.source.CanonicalURI, err)
 	}
@@ -147,56 +155,137 @@ func (adapterFS *AdapterFS) Index() (err error) {
 
 	adapterFS.source.CanonicalURI = canonicalURI.String()
 
-	if documents, err = adapterFS.database.Query(ColSources).Where(
-		clover.Field("urn").Eq(adapterFS.source.MarshalURN()),
-	).FindAll(); err != nil {
+	if document, isInserted, err = findOrInsertByURN(
+		adapterFS.database,
+		ColSources,
+		adapterFS.source.MarshalURN(),
+		func() (document *clover.Document, err error) {
+			var pathStat fs.FileInfo
+
+			if pathStat, err = os.Stat(canonicalURI.Path); err != nil {
+				return
+			}
+
+			adapterFS.source.AdapterData = &AdapterDataFS{
+				Path:  canonicalURI.Path,
+				IsDir: pathStat.IsDir(),
+				IsDot: strings.HasPrefix(Path.Base(canonicalURI.Path), "."),
+			}
+
+			document = clover.NewDocument()
+			document.SetAll(adapterFS.source.MarshalMap())
+
+			if adapterFS.source.ID != "" {
+				document.Set(FieldObjectID, adapterFS.source.ID)
+			}
+
+			return
+		},
+	); err != nil {
 		return
 	}
 
-	if len(documents) > 0 {
-		adapterFS.source.ID = documents[0].ObjectId()
+	adapterFS.source.ID = document.ObjectId()
+
+	if isInserted {
+		record := make(Record).
+			SetType(RecordSource).
+			SetAll(adapterFS.source.MarshalMap())
 
-		if err = adapterFS.source.UnmarshalDBDocument(documents[0]); err != nil {
+		if err = adapterFS.index.Index(adapterFS.source.ID, record); err != nil {
 			return
 		}
 
-		err = adapterFS.crawlPath(".")
+		adapterFS.events.PublishSource(protocol.EventType_SOURCE_ADDED, adapterFS.source)
+	} else if err = adapterFS.source.UnmarshalDBDocument(document); err != nil {
 		return
 	}
 
-	if pathStat, err = os.Stat(canonicalURI.Path); err != nil {
-		if os.IsNotExist(err) {
-			return
-		}
+	adapterFS.source.Stats.BytesIndexed = 0
+	adapterFS.crawledIDs = map[string]bool{}
+	adapterFS.isPartial = false
 
+	if err = adapterFS.crawlPath("."); err != nil {
 		return
 	}
 
-	adapterFS.source.AdapterData = &AdapterDataFS{
-		Path:  canonicalURI.Path,
-		IsDir: pathStat.IsDir(),
-		IsDot: strings.HasPrefix(Path.Base(canonicalURI.Path), "."),
+	// the files that could not be crawled may still be there
+	if !adapterFS.isPartial {
+		err = adapterFS.purgeRemovedFiles(nil)
 	}
 
-	document := clover.NewDocument()
-	document.SetAll(adapterFS.source.MarshalMap())
+	return
+}
 
-	if adapterFS.source.ID, err = adapterFS.database.InsertOne(ColSources, document); err != nil {
+// IndexPaths indexes again only some paths of an indexed directory source,
+// relative to it: the files and directories found there are crawled, and the
+// resources of those no longer found are removed
+func (adapterFS *AdapterFS) IndexPaths(job *Job, paths []string) (err error) {
+	var documents []*clover.Document
+
+	adapterFS.job = job
+	adapterFS.crawledIDs = map[string]bool{}
+	adapterFS.isPartial = false
+
+	if adapterDataFS, isFS := adapterFS.source.AdapterData.(*AdapterDataFS); !isFS || !adapterDataFS.IsDir {
+		return fmt.Errorf("cannot index paths of file source '%s'", adapterFS.source.CanonicalURI)
+	}
+
+	if documents, err = adapterFS.database.Query(ColResources).Where(
+		clover.Field("contextId").Eq(adapterFS.source.ContextID).And(clover.Field("sourceId").Eq(adapterFS.source.ID)),
+	).FindAll(); err != nil {
 		return
 	}
 
-	record := make(Record).
-		SetType(RecordSource).
-		SetAll(adapterFS.source.MarshalMap())
+	pathDocuments := make([]*clover.Document, 0)
 
-	if err = adapterFS.index.Index(adapterFS.source.ID, record); err != nil {
-		return
+	for _, document := range documents {
+		resourcePath, _ := document.Get(fmt.Sprintf("%s.path", ResFSFile)).(string)
+
+		if !isUnderPaths(resourcePath, paths) {
+			continue
+		}
+
+		pathDocuments = append(pathDocuments, document)
+
+		// the crawl counts again the bytes of the files still there; older
+		// rebuilds of the index left a size of -1 to the files to read again
+		if size, isInt64 := document.Get(fmt.Sprintf("%s.size", ResFSFile)).(int64); isInt64 && size > 0 {
+			adapterFS.source.Stats.BytesIndexed -= size
+		}
+	}
+
+	for _, path := range paths {
+		if err = adapterFS.crawlPath(path); errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		} else if err != nil {
+			if !job.tolerate(err) {
+				return
+			}
+
+			adapterFS.isPartial = true
+		}
+	}
+
+	if !adapterFS.isPartial {
+		err = adapterFS.purgeRemovedFiles(pathDocuments)
 	}
 
-	err = adapterFS.crawlPath(".")
 	return
 }
 
+// isUnderPaths tells whether a path relative to a source is one of the paths
+// or lies within one of them
+func isUnderPaths(resourcePath string, paths []string) bool {
+	for _, path := range paths {
+		if path == "." || resourcePath == path || strings.HasPrefix(resourcePath, path+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (adapterFS *AdapterFS) crawlPath(path string) (err error) {
 	var (
 		adapterDataFS = adapterFS.source.AdapterData.(*AdapterDataFS)
@@ -217,6 +306,10 @@ func (adapterFS *AdapterFS) crawlPath(path string) (err error) {
 		return
 	}
 
+	if err = adapterFS.job.checkpoint(); err != nil {
+		return
+	}
+
 	if resourceStat, err = os.Stat(resourceURI.Path); err != nil {
 		return
 	}
@@ -232,54 +325,129 @@ func (adapterFS *AdapterFS) crawlPath(path string) (err error) {
 			if err = adapterFS.crawlPath(
 				Path.Join(path, entry.Name()),
 			); err != nil {
-				return
+				if !adapterFS.job.tolerate(err) {
+					return
+				}
+
+				adapterFS.isPartial = true
 			}
 		}
 
+		err = nil
 		return
 	}
 
 	var (
-		documents []*clover.Document
-		data      []byte
+		document   *clover.Document
+		isInserted bool
+		data       []byte
+		change     = resourceAdded
 	)
 
 	resourceFSFile := NewResourceFSFile(adapterFS.source, path)
 
-	if documents, err = adapterFS.database.Query(ColResources).Where(
-		clover.Field("urn").Eq(resourceFSFile.MarshalURN()),
-	).FindAll(); err != nil {
+	if document, isInserted, err = findOrInsertByURN(
+		adapterFS.database,
+		ColResources,
+		resourceFSFile.MarshalURN(),
+		func() (*clover.Document, error) {
+			document := clover.NewDocument()
+			document.SetAll(resourceFSFile.MarshalMap())
+			return document, nil
+		},
+	); err != nil {
 		return
 	}
 
-	if len(documents) > 0 {
-		resourceFSFile.SetID(documents[0].ObjectId())
+	resourceFSFile.SetID(document.ObjectId())
 
-		if err = resourceFSFile.UnmarshalDBDocument(documents[0]); err != nil {
+	adapterFS.crawledIDs[document.ObjectId()] = true
+	adapterFS.source.Stats.BytesIndexed += resourceStat.Size()
+
+	if !isInserted {
+		if err = resourceFSFile.UnmarshalDBDocument(document); err != nil {
 			return
 		}
-	} else {
-		var resourceFSFileID string
-
-		document := clover.NewDocument()
-		document.SetAll(resourceFSFile.MarshalMap())
 
-		if resourceFSFileID, err = adapterFS.database.InsertOne(ColResources, document); err != nil {
+		if resourceFSFile.isUnchanged(resourceStat) {
+			adapterFS.job.resourceProcessed(resourceUnchanged)
 			return
 		}
 
-		resourceFSFile.SetID(resourceFSFileID)
+		change = resourceUpdated
 	}
 
+	indexedHash := resourceFSFile.hash
+
 	if data, err = resourceFSFile.readFile(adapterFS); err != nil {
 		return
 	}
 
+	// a file touched but not changed is left as indexed
+	if change == resourceUpdated && resourceFSFile.hash == indexedHash {
+		if err = resourceFSFile.updateFileState(adapterFS.database); err != nil {
+			return
+		}
+
+		adapterFS.job.resourceProcessed(resourceUnchanged)
+		return
+	}
+
 	if err = resourceFSFile.parseFile(adapterFS, data); err != nil {
 		return
 	}
 
-	err = resourceFSFile.Index(adapterFS)
+	if err = resourceFSFile.Index(adapterFS); err != nil {
+		return
+	}
+
+	if err = resourceFSFile.updateFileState(adapterFS.database); err != nil {
+		return
+	}
+
+	adapterFS.events.PublishResourceIndexed(resourceFSFile)
+	adapterFS.job.resourceProcessed(change)
+	return
+}
+
+// purgeRemovedFiles removes, among the given resource documents or else all
+// those of the source, the resources whose files the crawl no longer found
+func (adapterFS *AdapterFS) purgeRemovedFiles(documents []*clover.Document) (err error) {
+	if documents == nil {
+		if documents, err = adapterFS.database.Query(ColResources).Where(
+			clover.Field("contextId").Eq(adapterFS.source.ContextID).And(clover.Field("sourceId").Eq(adapterFS.source.ID)),
+		).FindAll(); err != nil {
+			return
+		}
+	}
+
+	removedIDs := make([]string, 0)
+	batch := adapterFS.index.NewBatch()
+
+	for _, document := range documents {
+		if !adapterFS.crawledIDs[document.ObjectId()] {
+			removedIDs = append(removedIDs, document.ObjectId())
+			batch.Delete(document.ObjectId())
+		}
+	}
+
+	if len(removedIDs) < 1 {
+		return
+	}
+
+	if err = adapterFS.index.Batch(batch); err != nil {
+		return
+	}
+
+	for _, resourceID := range removedIDs {
+		if err = adapterFS.database.Query(ColResources).DeleteById(resourceID); err != nil {
+			return
+		}
+
+		adapterFS.events.PublishResourceRemoved(adapterFS.source.ContextID, resourceID)
+	}
+
+	adapterFS.job.resourcesRemoved(len(removedIDs))
 	return
 }
 
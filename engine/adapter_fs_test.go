@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// reindexTestPaths reindexes some paths of a source, as its watcher would, and
+// waits for it
+func reindexTestPaths(t *testing.T, engine *Engine, context *Context, source *Source, paths ...string) (job *Job, reindexedSource *Source) {
+	t.Helper()
+
+	job = engine.runJob(context, source.CanonicalURI, func(job *Job) (*Source, error) {
+		return context.reindexPaths(source.ID, paths, job)
+	})
+	job.wait()
+
+	reindexedSource, err := context.GetSource(source.ID)
+	if err != nil {
+		t.Fatalf("getting the source: %+v", err)
+	}
+
+	return
+}
+
+func assertJobCounts(t *testing.T, job *Job, added, updated, unchanged, removed int64) {
+	t.Helper()
+
+	if job.ResourcesAdded != added || job.ResourcesUpdated != updated || job.ResourcesUnchanged != unchanged || job.ResourcesRemoved != removed {
+		t.Errorf(
+			"job added %d, updated %d, left %d unchanged and removed %d, want %d, %d, %d and %d",
+			job.ResourcesAdded, job.ResourcesUpdated, job.ResourcesUnchanged, job.ResourcesRemoved,
+			added, updated, unchanged, removed,
+		)
+	}
+}
+
+func assertResourcesTotal(t *testing.T, context *Context, want int) {
+	t.Helper()
+
+	_, total, err := context.GetResources(1, 0)
+	if err != nil {
+		t.Fatalf("listing the resources: %+v", err)
+	}
+
+	if total != want {
+		t.Errorf("%d resources, want %d", total, want)
+	}
+}
+
+func TestIndexPaths(t *testing.T) {
+	engine, defaultContext := newTestEngine(t)
+
+	uri := newTestSourceDirOf(t, map[string]string{
+		"a.txt":     "alpha",
+		"b.txt":     "bravo",
+		"sub/c.txt": "charlie",
+	})
+	dir := strings.TrimPrefix(uri, "file://")
+
+	_, source := indexTestSource(t, engine, defaultContext, uri)
+
+	if source.Stats.BytesIndexed != 17 {
+		t.Errorf("%d bytes indexed, want 17", source.Stats.BytesIndexed)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), []byte("bravo bravo"), 0644); err != nil {
+		t.Fatalf("changing a file: %+v", err)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "sub", "c.txt")); err != nil {
+		t.Fatalf("removing a file: %+v", err)
+	}
+
+	job, source := reindexTestPaths(t, engine, defaultContext, source, "a.txt", "b.txt", "sub")
+
+	assertJobCounts(t, job, 0, 1, 1, 1)
+	assertResourcesTotal(t, defaultContext, 2)
+
+	if source.Stats.BytesIndexed != 16 {
+		t.Errorf("%d bytes indexed, want 16", source.Stats.BytesIndexed)
+	}
+}
+
+// TestIndexPathsPartial reindexes a directory holding a file which cannot be
+// crawled; the resources not found are kept, as they may still be there
+func TestIndexPathsPartial(t *testing.T) {
+	engine, defaultContext := newTestEngine(t)
+
+	uri := newTestSourceDirOf(t, map[string]string{
+		"a.txt":     "alpha",
+		"sub/c.txt": "charlie",
+	})
+	dir := strings.TrimPrefix(uri, "file://")
+
+	_, source := indexTestSource(t, engine, defaultContext, uri)
+
+	if err := os.Remove(filepath.Join(dir, "sub", "c.txt")); err != nil {
+		t.Fatalf("removing a file: %+v", err)
+	}
+
+	if err := os.Symlink(filepath.Join(dir, "missing.txt"), filepath.Join(dir, "sub", "broken.txt")); err != nil {
+		t.Fatalf("linking a missing file: %+v", err)
+	}
+
+	job, _ := reindexTestPaths(t, engine, defaultContext, source, "sub")
+
+	if len(job.Errors) != 1 {
+		t.Errorf("job errors %v, want the broken link's", job.Errors)
+	}
+
+	assertJobCounts(t, job, 0, 0, 0, 0)
+	assertResourcesTotal(t, defaultContext, 2)
+}
+
+// TestIndexPathsAfterRebuild reindexes a file whose size the rebuild of the
+// index forgot, without counting its bytes twice
+func TestIndexPathsAfterRebuild(t *testing.T) {
+	engine, defaultContext := newTestEngine(t)
+
+	_, source := indexTestSource(t, engine, defaultContext, newTestSourceDirOf(t, map[string]string{
+		"a.txt": "alpha",
+		"b.txt": "bravo",
+	}))
+
+	if err := defaultContext.rebuildIndex(); err != nil {
+		t.Fatalf("rebuilding the index: %+v", err)
+	}
+
+	job, source := reindexTestPaths(t, engine, defaultContext, source, "a.txt")
+
+	assertJobCounts(t, job, 0, 1, 0, 0)
+
+	if source.Stats.BytesIndexed != 10 {
+		t.Errorf("%d bytes indexed, want 10", source.Stats.BytesIndexed)
+	}
+}
@@ -1,7 +1,9 @@
 package engine
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"mime"
 	"net/http"
 	"net/url"
@@ -80,13 +82,16 @@ type AdapterWeb struct {
 	source   *Source
 	database *clover.DB
 	index    bleve.Index
+	events   *EventBus
+	job      *Job
 }
 
-func NewAdapterWeb(source *Source, database *clover.DB, index bleve.Index) *AdapterWeb {
+func NewAdapterWeb(source *Source, database *clover.DB, index bleve.Index, events *EventBus) *AdapterWeb {
 	return &AdapterWeb{
 		source:   source,
 		database: database,
 		index:    index,
+		events:   events,
 	}
 }
 
@@ -110,12 +115,15 @@ func (adapterWeb *AdapterWeb) UnmarshalDBDocument(document *clover.Document) err
 	return adapterWeb.source.AdapterData.UnmarshalDBDocument(document)
 }
 
-func (adapterWeb *AdapterWeb) Index() (err error) {
+func (adapterWeb *AdapterWeb) Index(job *Job) (err error) {
 	var (
-		parsedURI *url.URL
-		documents []*clover.Document
+		parsedURI  *url.URL
+		document   *clover.Document
+		isInserted bool
 	)
 
+	adapterWeb.job = job
+
 	if parsedURI, err = url.Parse(adapterWeb.source.CanonicalURI); err != nil {
 		return fmt.Errorf("invalid URI '%s': %+v", adapterWeb.source.CanonicalURI, err)
 	}
@@ -132,41 +140,43 @@ func (adapterWeb *AdapterWeb) Index() (err error) {
 
 	adapterWeb.source.CanonicalURI = canonicalURI.String()
 
-	if documents, err = adapterWeb.database.Query(ColSources).Where(
-		clover.Field("urn").Eq(adapterWeb.source.MarshalURN()),
-	).FindAll(); err != nil {
+	if document, isInserted, err = findOrInsertByURN(
+		adapterWeb.database,
+		ColSources,
+		adapterWeb.source.MarshalURN(),
+		func() (*clover.Document, error) {
+			adapterWeb.source.AdapterData = &AdapterDataWeb{
+				Scheme: canonicalURI.Scheme,
+				Host:   canonicalURI.Host,
+				User:   canonicalURI.User.String(),
+			}
+
+			document := clover.NewDocument()
+			document.SetAll(adapterWeb.source.MarshalMap())
+
+			if adapterWeb.source.ID != "" {
+				document.Set(FieldObjectID, adapterWeb.source.ID)
+			}
+
+			return document, nil
+		},
+	); err != nil {
 		return
 	}
 
-	if len(documents) > 0 {
-		adapterWeb.source.ID = documents[0].ObjectId()
+	adapterWeb.source.ID = document.ObjectId()
 
-		if err = adapterWeb.source.UnmarshalDBDocument(documents[0]); err != nil {
+	if isInserted {
+		record := make(Record).
+			SetType(RecordSource).
+			SetAll(adapterWeb.source.MarshalMap())
+
+		if err = adapterWeb.index.Index(adapterWeb.source.ID, record); err != nil {
 			return
 		}
 
-		err = adapterWeb.crawlURI(parsedURI)
-		return
-	}
-
-	adapterWeb.source.AdapterData = &AdapterDataWeb{
-		Scheme: canonicalURI.Scheme,
-		Host:   canonicalURI.Host,
-		User:   canonicalURI.User.String(),
-	}
-
-	document := clover.NewDocument()
-	document.SetAll(adapterWeb.source.MarshalMap())
-
-	if adapterWeb.source.ID, err = adapterWeb.database.InsertOne(ColSources, document); err != nil {
-		return
-	}
-
-	record := make(Record).
-		SetType(RecordSource).
-		SetAll(adapterWeb.source.MarshalMap())
-
-	if err = adapterWeb.index.Index(adapterWeb.source.ID, record); err != nil {
+		adapterWeb.events.PublishSource(protocol.EventType_SOURCE_ADDED, adapterWeb.source)
+	} else if err = adapterWeb.source.UnmarshalDBDocument(document); err != nil {
 		return
 	}
 
@@ -180,14 +190,21 @@ func (adapterWeb *AdapterWeb) crawlURI(resourceURI *url.URL) (err error) {
 	}
 
 	var (
+		request     *http.Request
 		response    *http.Response
 		contentType string
 	)
 
-	if response, err = http.Get(resourceURI.String()); err != nil {
+	if request, err = http.NewRequestWithContext(adapterWeb.job.Context(), http.MethodGet, resourceURI.String(), nil); err != nil {
+		return
+	}
+
+	if response, err = http.DefaultClient.Do(request); err != nil {
 		return
 	}
 
+	defer response.Body.Close()
+
 	if response.StatusCode < 200 || response.StatusCode >= 300 {
 		return fmt.Errorf("failed with code %d; GET %s", response.StatusCode, resourceURI.String())
 	}
@@ -212,40 +229,67 @@ func (adapterWeb *AdapterWeb) crawlURI(resourceURI *url.URL) (err error) {
 }
 
 func (adapterWeb *AdapterWeb) processResponseHTML(resourceURI *url.URL, response *http.Response) (err error) {
-	var documents []*clover.Document
+	var (
+		document   *clover.Document
+		isInserted bool
+		data       []byte
+	)
 
 	resourceWebPage := NewResourceWebPage(adapterWeb.source, resourceURI)
 
-	if documents, err = adapterWeb.database.Query(ColResources).Where(
-		clover.Field("urn").Eq(resourceWebPage.MarshalURN()),
-	).FindAll(); err != nil {
+	if document, isInserted, err = findOrInsertByURN(
+		adapterWeb.database,
+		ColResources,
+		resourceWebPage.MarshalURN(),
+		func() (*clover.Document, error) {
+			document := clover.NewDocument()
+			document.SetAll(resourceWebPage.MarshalMap())
+			return document, nil
+		},
+	); err != nil {
 		return
 	}
 
-	if len(documents) > 0 {
-		resourceWebPage.SetID(documents[0].ObjectId())
+	resourceWebPage.SetID(document.ObjectId())
 
-		if err = resourceWebPage.UnmarshalDBDocument(documents[0]); err != nil {
+	change := resourceAdded
+
+	if !isInserted {
+		if err = resourceWebPage.UnmarshalDBDocument(document); err != nil {
 			return
 		}
-	} else {
-		var resourceWebPageID string
 
-		document := clover.NewDocument()
-		document.SetAll(resourceWebPage.MarshalMap())
+		change = resourceUpdated
+	}
 
-		if resourceWebPageID, err = adapterWeb.database.InsertOne(ColResources, document); err != nil {
-			return
-		}
+	if data, err = io.ReadAll(response.Body); err != nil {
+		return
+	}
+
+	// each page is indexed on its own, so the source's bytes are kept up to
+	// date by the difference in size of the page
+	previousSize := resourceWebPage.size
+	resourceWebPage.size = int64(len(data))
+	adapterWeb.source.Stats.BytesIndexed += resourceWebPage.size - previousSize
+
+	resourceWebPage.parseResponseHeaders(response)
 
-		resourceWebPage.SetID(resourceWebPageID)
+	if err = resourceWebPage.parseHTML(bytes.NewReader(data)); err != nil {
+		return
+	}
+
+	if err = resourceWebPage.Index(adapterWeb); err != nil {
+		return
 	}
 
-	if err = resourceWebPage.parseHTML(response.Body); err != nil {
+	if err = adapterWeb.database.Query(ColResources).UpdateById(*resourceWebPage.ID(), map[string]interface{}{
+		fmt.Sprintf("%s.size", ResWebPage): resourceWebPage.size,
+	}); err != nil {
 		return
 	}
 
-	err = resourceWebPage.Index(adapterWeb)
+	adapterWeb.events.PublishResourceIndexed(resourceWebPage)
+	adapterWeb.job.resourceProcessed(change)
 	return
 }
 
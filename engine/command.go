@@ -0,0 +1,603 @@
+package engine
+
+import (
+	_context "context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"risp/protocol"
+)
+
+/**
+ * Command : An entry of the Execute command language, named by one or more
+ * words (e.g. "context use") and followed by its arguments
+ */
+
+type Command struct {
+	Name         string
+	Usage        string
+	Description  string
+	MinArgs      int
+	MaxArgs      int // negative for no limit
+	needsContext bool
+	run          func(engine *Engine, call *CommandCall) error
+}
+
+type CommandCall struct {
+	RPCContext _context.Context
+	Context    *Context
+	Args       []string
+	Response   *protocol.ExecuteResponse
+}
+
+func (command *Command) MarshalProtocol() *protocol.Command {
+	return &protocol.Command{
+		Name:        command.Name,
+		Usage:       command.Usage,
+		Description: command.Description,
+	}
+}
+
+func (call *CommandCall) print(format string, args ...interface{}) {
+	call.Response.Output = append(call.Response.Output, fmt.Sprintf(format, args...))
+}
+
+// commandNameWords is the most words a command name is made of
+const commandNameWords = 2
+
+var (
+	commands       []*Command
+	commandsByName map[string]*Command
+)
+
+func init() {
+	commands = []*Command{
+		{
+			Name:         "source",
+			Usage:        "source <uri>",
+			Description:  "Index a file or web URI into the context",
+			MinArgs:      1,
+			MaxArgs:      1,
+			needsContext: true,
+			run:          (*Engine).commandSource,
+		},
+		{
+			Name:         "unsource",
+			Usage:        "unsource <uri|id>",
+			Description:  "Remove a source and purge its resources from the context",
+			MinArgs:      1,
+			MaxArgs:      1,
+			needsContext: true,
+			run:          (*Engine).commandUnsource,
+		},
+		{
+			Name:         "reindex",
+			Usage:        "reindex [uri|id]",
+			Description:  "Index again one, or every, source of the context",
+			MinArgs:      0,
+			MaxArgs:      1,
+			needsContext: true,
+			run:          (*Engine).commandReindex,
+		},
+		{
+			Name:         "jobs",
+			Usage:        "jobs [id]",
+			Description:  "List the indexing jobs of the context, or one of them, with the resources each added, updated, left unchanged and removed",
+			MinArgs:      0,
+			MaxArgs:      1,
+			needsContext: true,
+			run:          (*Engine).commandJobs,
+		},
+		{
+			Name:        "context use",
+			Usage:       "context use <id|name>",
+			Description: "Switch to another context",
+			MinArgs:     1,
+			MaxArgs:     1,
+			run:         (*Engine).commandContextUse,
+		},
+		{
+			Name:        "context create",
+			Usage:       "context create <name>",
+			Description: "Create a new context",
+			MinArgs:     1,
+			MaxArgs:     1,
+			run:         (*Engine).commandContextCreate,
+		},
+		{
+			Name:        "context rm",
+			Usage:       "context rm <id|name>",
+			Description: "Delete a context along with its sources and resources",
+			MinArgs:     1,
+			MaxArgs:     1,
+			run:         (*Engine).commandContextRemove,
+		},
+		{
+			Name:         "sources",
+			Usage:        "sources [limit] [offset]",
+			Description:  "List the sources of the context",
+			MinArgs:      0,
+			MaxArgs:      2,
+			needsContext: true,
+			run:          (*Engine).commandSources,
+		},
+		{
+			Name:         "resources",
+			Usage:        "resources [limit] [offset]",
+			Description:  "List the resources of the context",
+			MinArgs:      0,
+			MaxArgs:      2,
+			needsContext: true,
+			run:          (*Engine).commandResources,
+		},
+		{
+			Name:         "history",
+			Usage:        "history [limit] [offset]",
+			Description:  "List the recent searches of the context",
+			MinArgs:      0,
+			MaxArgs:      2,
+			needsContext: true,
+			run:          (*Engine).commandHistory,
+		},
+		{
+			Name:         "history misses",
+			Usage:        "history misses [limit] [offset]",
+			Description:  "List the recent searches of the context that found nothing",
+			MinArgs:      0,
+			MaxArgs:      2,
+			needsContext: true,
+			run:          (*Engine).commandHistoryMisses,
+		},
+		{
+			Name:         "history clear",
+			Usage:        "history clear",
+			Description:  "Forget the searches of the context",
+			MinArgs:      0,
+			MaxArgs:      0,
+			needsContext: true,
+			run:          (*Engine).commandHistoryClear,
+		},
+		{
+			Name:         "export",
+			Usage:        "export <path> [context id|name...]",
+			Description:  "Export contexts, the current one by default, to a YAML dump",
+			MinArgs:      1,
+			MaxArgs:      -1,
+			needsContext: true,
+			run:          (*Engine).commandExport,
+		},
+		{
+			Name:        "help",
+			Usage:       "help [command]",
+			Description: "Describe the available commands",
+			MinArgs:     0,
+			MaxArgs:     commandNameWords,
+			run:         (*Engine).commandHelp,
+		},
+	}
+
+	commandsByName = map[string]*Command{}
+
+	for _, command := range commands {
+		commandsByName[command.Name] = command
+	}
+}
+
+// parseCommandLine splits a command line into words on whitespace; single
+// quotes keep their contents verbatim, while a backslash escapes the next
+// character both unquoted and within double quotes
+func parseCommandLine(line string) (words []string, err error) {
+	var (
+		word      strings.Builder
+		hasWord   bool
+		quote     rune
+		isEscaped bool
+	)
+
+	words = make([]string, 0)
+
+	for _, char := range line {
+		switch true {
+		case isEscaped:
+			word.WriteRune(char)
+			isEscaped = false
+		case quote == '\'':
+			if char == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(char)
+			}
+		case char == '\\':
+			isEscaped = true
+			hasWord = true
+		case quote == '"':
+			if char == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(char)
+			}
+		case char == '\'' || char == '"':
+			quote = char
+			hasWord = true
+		case unicode.IsSpace(char):
+			if hasWord {
+				words = append(words, word.String())
+				word.Reset()
+				hasWord = false
+			}
+		default:
+			word.WriteRune(char)
+			hasWord = true
+		}
+	}
+
+	if isEscaped {
+		return nil, fmt.Errorf("unterminated escape at the end of the command")
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+
+	if hasWord {
+		words = append(words, word.String())
+	}
+
+	return
+}
+
+// findCommand matches the longest command name the words start with
+func findCommand(words []string) (command *Command, args []string) {
+	for nameWords := commandNameWords; nameWords > 0; nameWords-- {
+		if len(words) < nameWords {
+			continue
+		}
+
+		if command = commandsByName[strings.Join(words[:nameWords], " ")]; command != nil {
+			return command, words[nameWords:]
+		}
+	}
+
+	return nil, nil
+}
+
+func parseCommandRange(args []string) (limit int, offset int, err error) {
+	limit = 100
+
+	if len(args) > 0 {
+		if limit, err = strconv.Atoi(args[0]); err != nil || limit < 1 {
+			return 0, 0, fmt.Errorf("invalid limit '%s'", args[0])
+		}
+	}
+
+	if len(args) > 1 {
+		if offset, err = strconv.Atoi(args[1]); err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("invalid offset '%s'", args[1])
+		}
+	}
+
+	return
+}
+
+func (engine *Engine) commandSource(call *CommandCall) (err error) {
+	job := engine.startJob(call.Context, call.Args[0])
+
+	call.Response.Jobs = append(call.Response.Jobs, job.MarshalProtocol())
+	call.print("Indexing '%s' in job %s", call.Args[0], job.ID)
+	return
+}
+
+func (engine *Engine) commandUnsource(call *CommandCall) (err error) {
+	var (
+		source          *Source
+		resourcesPurged int
+	)
+
+	if source, err = call.Context.FindSource(call.Args[0]); err != nil {
+		call.Response.Error = NewProtocolError(ErrInvalidSource, err)
+		return nil
+	}
+
+	if resourcesPurged, err = call.Context.DeleteSource(source); err != nil {
+		return
+	}
+
+	call.Response.Total = int64(resourcesPurged)
+	call.print("Purged %d resource(s) of '%s'", resourcesPurged, source.CanonicalURI)
+	return
+}
+
+func (engine *Engine) commandReindex(call *CommandCall) (err error) {
+	var jobs []*Job
+
+	if len(call.Args) > 0 {
+		var (
+			source *Source
+			uris   []string
+		)
+
+		if source, err = call.Context.FindSource(call.Args[0]); err != nil {
+			call.Response.Error = NewProtocolError(ErrInvalidSource, err)
+			return nil
+		}
+
+		if uris, err = call.Context.GetSourceURIsOf(source); err != nil {
+			return
+		}
+
+		jobs = []*Job{engine.startSourceJob(call.Context, source.CanonicalURI, uris, "", nil)}
+	} else if jobs, err = engine.reindexSources(call.Context, call.Context); err != nil {
+		return
+	}
+
+	for _, job := range jobs {
+		call.Response.Jobs = append(call.Response.Jobs, job.MarshalProtocol())
+		call.print("Reindexing '%s' in job %s", job.URI, job.ID)
+	}
+
+	if len(jobs) > 0 {
+		call.print("See 'jobs <id>' for the resources added, updated, unchanged and removed")
+	}
+
+	call.Response.Total = int64(len(jobs))
+	return
+}
+
+func (engine *Engine) commandJobs(call *CommandCall) (err error) {
+	jobs := make([]*protocol.Job, 0)
+
+	for _, job := range engine.getJobs(call.Context.ID) {
+		if len(call.Args) < 1 || job.ID == call.Args[0] {
+			jobs = append(jobs, job.MarshalProtocol())
+		}
+	}
+
+	if len(call.Args) > 0 && len(jobs) < 1 {
+		call.Response.Error = NewProtocolError(ErrInvalidJob, fmt.Sprintf("Job Not Found: '%s'", call.Args[0]))
+		return
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].StartedAt < jobs[j].StartedAt
+	})
+
+	for _, job := range jobs {
+		call.Response.Jobs = append(call.Response.Jobs, job)
+		call.print(
+			"%s  %s  %s  added %d, updated %d, unchanged %d, removed %d",
+			job.Id,
+			strings.ToLower(job.Status.String()),
+			job.Uri,
+			job.ResourcesAdded,
+			job.ResourcesUpdated,
+			job.ResourcesUnchanged,
+			job.ResourcesRemoved,
+		)
+	}
+
+	call.Response.Total = int64(len(jobs))
+	call.print("%d job(s)", len(jobs))
+	return
+}
+
+func (engine *Engine) commandContextUse(call *CommandCall) (err error) {
+	targetContext, hasContext := engine.findContext(call.Args[0])
+	if !hasContext {
+		call.Response.Error = NewProtocolError(ErrInvalidContext, fmt.Sprintf("Context Not Found: '%s'", call.Args[0]))
+		return
+	}
+
+	call.Response.Context = targetContext.MarshalProtocol()
+	call.print("Using context '%s' (%s)", call.Response.Context.Name, targetContext.ID)
+	return
+}
+
+func (engine *Engine) commandContextCreate(call *CommandCall) (err error) {
+	var createdContext *Context
+
+	if createdContext, err = engine.createContext(&Context{
+		Name: call.Args[0],
+	}); err != nil {
+		return
+	}
+
+	call.Response.Context = createdContext.MarshalProtocol()
+	call.print("Created context '%s' (%s)", call.Response.Context.Name, createdContext.ID)
+	return
+}
+
+func (engine *Engine) commandContextRemove(call *CommandCall) (err error) {
+	targetContext, hasContext := engine.findContext(call.Args[0])
+	if !hasContext {
+		call.Response.Error = NewProtocolError(ErrInvalidContext, fmt.Sprintf("Context Not Found: '%s'", call.Args[0]))
+		return
+	}
+
+	if err = engine.deleteContext(targetContext); err == errDefaultContext {
+		call.Response.Error = NewProtocolError(ErrInvalidContext, "Cannot delete the default context, set another context as default first")
+		return nil
+	} else if err == errContextNotFound {
+		call.Response.Error = NewProtocolError(ErrInvalidContext, fmt.Sprintf("Context Not Found: '%s'", call.Args[0]))
+		return nil
+	} else if err != nil {
+		return
+	}
+
+	call.Response.Context = targetContext.MarshalProtocol()
+	call.print("Deleted context '%s' (%s)", call.Response.Context.Name, targetContext.ID)
+	return
+}
+
+func (engine *Engine) commandSources(call *CommandCall) (err error) {
+	var (
+		limit, offset int
+		sources       []*Source
+		sourcesTotal  int
+	)
+
+	if limit, offset, err = parseCommandRange(call.Args); err != nil {
+		call.Response.Error = NewProtocolError(ErrInvalidCommand, err)
+		return nil
+	}
+
+	if sources, sourcesTotal, err = call.Context.GetSources(limit, offset); err != nil {
+		return
+	}
+
+	call.Response.Total = int64(sourcesTotal)
+
+	for _, source := range sources {
+		call.Response.Sources = append(call.Response.Sources, source.MarshalProtocol())
+		call.print("%s  %s", source.ID, source.CanonicalURI)
+	}
+
+	call.print("%d of %d source(s)", len(sources), sourcesTotal)
+	return
+}
+
+func (engine *Engine) commandResources(call *CommandCall) (err error) {
+	var (
+		limit, offset  int
+		resources      []Resource
+		resourcesTotal int
+	)
+
+	if limit, offset, err = parseCommandRange(call.Args); err != nil {
+		call.Response.Error = NewProtocolError(ErrInvalidCommand, err)
+		return nil
+	}
+
+	if resources, resourcesTotal, err = call.Context.GetResources(limit, offset); err != nil {
+		return
+	}
+
+	call.Response.Total = int64(resourcesTotal)
+
+	for _, resource := range resources {
+		resourceProto := resource.MarshalProtocol()
+
+		call.Response.Resources = append(call.Response.Resources, resourceProto)
+		call.print("%s  %s%s", resourceProto.Id, resourceProto.SourceCanonicalUri, resourceProto.CanonicalUri)
+	}
+
+	call.print("%d of %d resource(s)", len(resources), resourcesTotal)
+	return
+}
+
+func (engine *Engine) commandHistory(call *CommandCall) (err error) {
+	return engine.printSearchHistory(call, false)
+}
+
+func (engine *Engine) commandHistoryMisses(call *CommandCall) (err error) {
+	return engine.printSearchHistory(call, true)
+}
+
+func (engine *Engine) printSearchHistory(call *CommandCall, zeroHits bool) (err error) {
+	var (
+		limit, offset int
+		entries       []*SearchHistoryEntry
+		entriesTotal  int
+	)
+
+	if limit, offset, err = parseCommandRange(call.Args); err != nil {
+		call.Response.Error = NewProtocolError(ErrInvalidCommand, err)
+		return nil
+	}
+
+	if entries, entriesTotal, err = call.Context.GetSearchHistory(limit, offset, zeroHits); err != nil {
+		return
+	}
+
+	call.Response.Total = int64(entriesTotal)
+
+	for _, entry := range entries {
+		call.print("%s  %6d hit(s)  %s", entry.SearchedAt.Format("2006-01-02 15:04"), entry.HitsTotal, entry.Value)
+	}
+
+	call.print("%d of %d search(es)", len(entries), entriesTotal)
+	return
+}
+
+func (engine *Engine) commandHistoryClear(call *CommandCall) (err error) {
+	var entriesCleared int
+
+	if entriesCleared, err = call.Context.ClearSearchHistory(); err != nil {
+		return
+	}
+
+	call.Response.Total = int64(entriesCleared)
+	call.print("Cleared %d search(es)", entriesCleared)
+	return
+}
+
+func (engine *Engine) commandExport(call *CommandCall) (err error) {
+	var exportResponse *protocol.ExportContextsResponse
+
+	request := &protocol.ExportContextsRequest{
+		OutputPath: call.Args[0],
+		ContextIds: []string{call.Context.ID},
+	}
+
+	if len(call.Args) > 1 {
+		request.ContextIds = make([]string, 0)
+
+		for _, contextIDOrName := range call.Args[1:] {
+			exportedContext, hasContext := engine.findContext(contextIDOrName)
+			if !hasContext {
+				call.Response.Error = NewProtocolError(ErrInvalidContext, fmt.Sprintf("Context Not Found: '%s'", contextIDOrName))
+				return
+			}
+
+			request.ContextIds = append(request.ContextIds, exportedContext.ID)
+		}
+	}
+
+	if exportResponse, err = engine.ExportContexts(call.RPCContext, request); err != nil {
+		return
+	}
+
+	if exportResponse.Error.GetCode() != ErrAllGood {
+		call.Response.Error = exportResponse.Error
+		return
+	}
+
+	call.Response.Total = int64(len(request.ContextIds))
+	call.print("Exported %d context(s) to '%s'", len(request.ContextIds), request.OutputPath)
+	return
+}
+
+func (engine *Engine) commandHelp(call *CommandCall) (err error) {
+	prefix := strings.Join(call.Args, " ")
+
+	for _, command := range commands {
+		if prefix != "" && command.Name != prefix && !strings.HasPrefix(command.Name, prefix+" ") {
+			continue
+		}
+
+		call.Response.Commands = append(call.Response.Commands, command.MarshalProtocol())
+		call.print("%-36s %s", command.Usage, command.Description)
+	}
+
+	if len(call.Response.Commands) < 1 {
+		call.Response.Error = NewProtocolError(ErrInvalidCommand, fmt.Sprintf("Invalid Command: '%s'", prefix))
+	}
+
+	return
+}
+
+func listCommands() (commandsProto []*protocol.Command) {
+	commandsProto = make([]*protocol.Command, 0, len(commands))
+
+	for _, command := range commands {
+		commandsProto = append(commandsProto, command.MarshalProtocol())
+	}
+
+	sort.Slice(commandsProto, func(i, j int) bool {
+		return commandsProto[i].Name < commandsProto[j].Name
+	})
+
+	return
+}
@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCommandLine(t *testing.T) {
+	for _, test := range []struct {
+		line    string
+		words   []string
+		isError bool
+	}{
+		{"", []string{}, false},
+		{"   ", []string{}, false},
+		{"context use  work", []string{"context", "use", "work"}, false},
+		{`source "file:///my docs"`, []string{"source", "file:///my docs"}, false},
+		{`source 'file:///my docs'`, []string{"source", "file:///my docs"}, false},
+		{`source file:///my\ docs`, []string{"source", "file:///my docs"}, false},
+		{`say "a \"quoted\" word"`, []string{"say", `a "quoted" word`}, false},
+		{`say 'no \escape'`, []string{"say", `no \escape`}, false},
+		{`say "it's"`, []string{"say", "it's"}, false},
+		{`say ""`, []string{"say", ""}, false},
+		{`say ab"c d"e`, []string{"say", "abc de"}, false},
+		{`say "unterminated`, nil, true},
+		{`say 'unterminated`, nil, true},
+		{`say escape\`, nil, true},
+	} {
+		words, err := parseCommandLine(test.line)
+
+		if (err != nil) != test.isError {
+			t.Errorf("parsing %q: error %v, want error %v", test.line, err, test.isError)
+			continue
+		}
+
+		if !test.isError && !reflect.DeepEqual(words, test.words) {
+			t.Errorf("parsing %q: %q, want %q", test.line, words, test.words)
+		}
+	}
+}
+
+func TestFindCommand(t *testing.T) {
+	for _, test := range []struct {
+		words []string
+		name  string
+		args  []string
+	}{
+		{[]string{"history"}, "history", []string{}},
+		{[]string{"history", "misses", "10"}, "history misses", []string{"10"}},
+		{[]string{"history", "10"}, "history", []string{"10"}},
+		{[]string{"context", "use", "work"}, "context use", []string{"work"}},
+		{[]string{"context"}, "", nil},
+		{[]string{"unknown", "use"}, "", nil},
+	} {
+		command, args := findCommand(test.words)
+
+		name := ""
+		if command != nil {
+			name = command.Name
+		}
+
+		if name != test.name || (command != nil && !reflect.DeepEqual(args, test.args)) {
+			t.Errorf("finding %q: %q %q, want %q %q", test.words, name, args, test.name, test.args)
+		}
+	}
+}
+
+func TestParseCommandRange(t *testing.T) {
+	for _, test := range []struct {
+		args          []string
+		limit, offset int
+		isError       bool
+	}{
+		{[]string{}, 100, 0, false},
+		{[]string{"10"}, 10, 0, false},
+		{[]string{"10", "20"}, 10, 20, false},
+		{[]string{"0"}, 0, 0, true},
+		{[]string{"ten"}, 0, 0, true},
+		{[]string{"10", "-1"}, 0, 0, true},
+	} {
+		limit, offset, err := parseCommandRange(test.args)
+
+		if (err != nil) != test.isError {
+			t.Errorf("parsing %q: error %v, want error %v", test.args, err, test.isError)
+			continue
+		}
+
+		if limit != test.limit || offset != test.offset {
+			t.Errorf("parsing %q: limit %d offset %d, want %d and %d", test.args, limit, offset, test.limit, test.offset)
+		}
+	}
+}
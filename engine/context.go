@@ -1,24 +1,38 @@
 package engine
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/query"
 	"github.com/necessitates/clover"
 
 	"risp/protocol"
 )
 
 type SearchResultHit struct {
-	Score      float64
-	Resource   Resource
-	Highlights map[string][]string
+	ContextID   string
+	Score       float64
+	Resource    Resource
+	Highlights  map[string][]string
+	Explanation *search.Explanation // of explained searches only
+	fields      map[string]interface{}
 }
 
 type SearchResult struct {
 	MaxScore float64
+	Total    uint64
+	Took     time.Duration
 	Hits     []*SearchResultHit
+	Facets   search.FacetResults
 }
 
 func (searchResult *SearchResult) MarshalProtocol() (response *protocol.QueryResponse) {
@@ -29,7 +43,9 @@ func (searchResult *SearchResult) MarshalProtocol() (response *protocol.QueryRes
 	}
 
 	response.MaxScore = float32(searchResult.MaxScore)
-	response.EdgesTotal = int64(len(searchResult.Hits))
+	response.EdgesTotal = int64(searchResult.Total)
+	response.Took = searchResult.Took.Milliseconds()
+	response.Facets = MarshalQueryFacets(searchResult.Facets)
 
 	for _, hit := range searchResult.Hits {
 		highlights := make([]*protocol.QueryHighlight, 0)
@@ -41,28 +57,45 @@ func (searchResult *SearchResult) MarshalProtocol() (response *protocol.QueryRes
 		}
 
 		response.Edges = append(response.Edges, &protocol.QueryHit{
-			Score:      float32(hit.Score),
-			Resource:   hit.Resource.MarshalProtocol(),
-			Highlights: highlights,
+			ContextId:   hit.ContextID,
+			Score:       float32(hit.Score),
+			Resource:    hit.Resource.MarshalProtocol(),
+			Highlights:  highlights,
+			Explanation: MarshalQueryExplanation(hit.Explanation),
 		})
 	}
 
 	return
 }
 
+var errResourceNotFound = errors.New("resource not found")
+
 type Context struct {
-	ID        string
-	Name      string
-	IsDefault bool
-	engine    *Engine
-	index     bleve.Index
+	ID                string
+	Name              string
+	IsDefault         bool
+	Weight            float64 // 0 weighs as defaultWeight
+	engine            *Engine
+	index             bleve.Index
+	isIndexClosed     bool
+	mutex             sync.RWMutex
+	savedQueriesMutex sync.Mutex // serializes the runs of the context's saved queries
 }
 
 func (context *Context) MarshalMap() (value map[string]interface{}) {
-	return map[string]interface{}{
+	context.mutex.RLock()
+	defer context.mutex.RUnlock()
+
+	value = map[string]interface{}{
 		"name":      context.Name,
 		"isDefault": context.IsDefault,
 	}
+
+	if context.Weight != 0 {
+		value["weight"] = context.Weight
+	}
+
+	return
 }
 
 func (context *Context) UnmarshalMap(value map[string]interface{}) error {
@@ -73,14 +106,22 @@ func (context *Context) UnmarshalMap(value map[string]interface{}) error {
 	context.Name = value["name"].(string)
 	context.IsDefault = value["isDefault"].(bool)
 
+	if weight, isWeight := unmarshalWeight(value["weight"]); isWeight {
+		context.Weight = weight
+	}
+
 	return nil
 }
 
 func (context *Context) MarshalProtocol() *protocol.Context {
+	context.mutex.RLock()
+	defer context.mutex.RUnlock()
+
 	return &protocol.Context{
 		Id:        context.ID,
 		Name:      context.Name,
 		IsDefault: context.IsDefault,
+		Weight:    context.weight(),
 	}
 }
 
@@ -92,18 +133,77 @@ func (context *Context) UnmarshalDBDocument(document *clover.Document) error {
 	context.Name = document.Get("name").(string)
 	context.IsDefault = document.Get("isDefault").(bool)
 
+	if weight, isWeight := unmarshalWeight(document.Get("weight")); isWeight {
+		context.Weight = weight
+	}
+
 	return nil
 }
 
+func (context *Context) getName() string {
+	context.mutex.RLock()
+	defer context.mutex.RUnlock()
+
+	return context.Name
+}
+
+func (context *Context) setName(name string) {
+	context.mutex.Lock()
+	defer context.mutex.Unlock()
+
+	context.Name = name
+}
+
+func (context *Context) getIsDefault() bool {
+	context.mutex.RLock()
+	defer context.mutex.RUnlock()
+
+	return context.IsDefault
+}
+
+func (context *Context) setIsDefault(isDefault bool) {
+	context.mutex.Lock()
+	defer context.mutex.Unlock()
+
+	context.IsDefault = isDefault
+}
+
+func (context *Context) weight() float64 {
+	if context.Weight == 0 {
+		return defaultWeight
+	}
+
+	return context.Weight
+}
+
+func (context *Context) getWeight() float64 {
+	context.mutex.RLock()
+	defer context.mutex.RUnlock()
+
+	return context.weight()
+}
+
+func (context *Context) setWeight(weight float64) {
+	context.mutex.Lock()
+	defer context.mutex.Unlock()
+
+	context.Weight = weight
+}
+
 func (context *Context) GetIndexPath() string {
 	return fmt.Sprintf("%s/%s", context.engine.config.PathData, context.ID)
 }
 
 func (context *Context) SourceURI(uri string) (source *Source, err error) {
+	return context.sourceURI(uri, "", nil)
+}
+
+func (context *Context) sourceURI(uri string, sourceID string, job *Job) (source *Source, err error) {
 	fmt.Printf("Source URI '%s'\n", uri)
 
 	source = &Source{
 		ContextID:    context.ID,
+		ID:           sourceID,
 		CanonicalURI: uri,
 	}
 
@@ -114,7 +214,35 @@ func (context *Context) SourceURI(uri string) (source *Source, err error) {
 		source.AdapterType = AdapterTypeWeb
 	}
 
-	err = source.Adapter(context.engine.database, context.index).Index()
+	err = source.Adapter(context.engine.database, context.index, context.engine.events).Index(job)
+
+	if source.ID != "" {
+		if statsErr := context.updateSourceStats(source, err); statsErr != nil && err == nil {
+			err = statsErr
+		}
+	}
+
+	return
+}
+
+// reindexPaths indexes again only some paths, relative to it, of an indexed
+// directory source
+func (context *Context) reindexPaths(sourceID string, paths []string, job *Job) (source *Source, err error) {
+	if source, err = context.GetSource(sourceID); err != nil {
+		return
+	}
+
+	adapterFS, isFS := source.Adapter(context.engine.database, context.index, context.engine.events).(*AdapterFS)
+	if !isFS {
+		return source, fmt.Errorf("cannot index paths of %s source '%s'", source.AdapterType, source.CanonicalURI)
+	}
+
+	err = adapterFS.IndexPaths(job, paths)
+
+	if statsErr := context.updateSourceStats(source, err); statsErr != nil && err == nil {
+		err = statsErr
+	}
+
 	return
 }
 
@@ -128,6 +256,10 @@ func (context *Context) GetSource(sourceID string) (source *Source, err error) {
 		return
 	}
 
+	if document == nil || document.Get("contextId") != context.ID {
+		return nil, fmt.Errorf("source '%s' not found", sourceID)
+	}
+
 	source = &Source{
 		ContextID: context.ID,
 		ID:        sourceID,
@@ -137,6 +269,50 @@ func (context *Context) GetSource(sourceID string) (source *Source, err error) {
 	return
 }
 
+func (context *Context) FindSource(sourceIDOrURI string) (source *Source, err error) {
+	var (
+		document     *clover.Document
+		canonicalURI string
+		adapterType  AdapterType
+		sources      []*Source
+	)
+
+	if document, err = context.engine.database.Query(ColSources).FindById(sourceIDOrURI); err != nil {
+		return
+	}
+
+	if document != nil && document.Get("contextId") == context.ID {
+		source = &Source{
+			ContextID: context.ID,
+			ID:        document.ObjectId(),
+		}
+
+		err = source.UnmarshalDBDocument(document)
+		return
+	}
+
+	if canonicalURI, adapterType, err = canonicalizeSourceURI(sourceIDOrURI); err != nil {
+		return nil, fmt.Errorf("source '%s' not found", sourceIDOrURI)
+	}
+
+	urn := (&Source{
+		ContextID:    context.ID,
+		CanonicalURI: canonicalURI,
+		AdapterType:  adapterType,
+	}).MarshalURN()
+
+	if sources, _, err = context.GetSourcesByCriteria(clover.Field("urn").Eq(urn), 1, 0); err != nil {
+		return
+	}
+
+	if len(sources) < 1 {
+		return nil, fmt.Errorf("source '%s' not found", sourceIDOrURI)
+	}
+
+	source = sources[0]
+	return
+}
+
 func (context *Context) GetSourcesByCriteria(criteria *clover.Criteria, limit, offset int) (sources []*Source, total int, err error) {
 	var (
 		documents  []*clover.Document
@@ -183,6 +359,143 @@ func (context *Context) GetSources(limit, offset int) (sources []*Source, total
 	return context.GetSourcesByCriteria(nil, limit, offset)
 }
 
+// eachSource visits, batch by batch, the sources of the context matching the
+// criteria, stopping at the first error
+func (context *Context) eachSource(criteria *clover.Criteria, visit func(source *Source) error) (err error) {
+	var (
+		sourcesOffset    = 0
+		sourcesBatchSize = 100
+	)
+
+	for {
+		var sources []*Source
+
+		if sources, _, err = context.GetSourcesByCriteria(criteria, sourcesBatchSize, sourcesOffset); err != nil {
+			return
+		}
+
+		for _, source := range sources {
+			if err = visit(source); err != nil {
+				return
+			}
+		}
+
+		if len(sources) < sourcesBatchSize {
+			return
+		}
+
+		sourcesOffset += sourcesBatchSize
+	}
+}
+
+// GetSourceURIsOf lists the URIs which, when sourced again, reproduce the
+// source; a web source is listed per page, since a web source itself only
+// covers its host
+func (context *Context) GetSourceURIsOf(source *Source) (uris []string, err error) {
+	if source.AdapterType != AdapterTypeWeb {
+		return []string{source.CanonicalURI}, nil
+	}
+
+	var (
+		resourcesOffset    = 0
+		resourcesBatchSize = 100
+		resourcesTotal     int
+	)
+
+	uris = make([]string, 0)
+
+	for {
+		var resources []Resource
+
+		if resources, resourcesTotal, err = context.GetResourcesByCriteria(
+			clover.Field("sourceId").Eq(source.ID),
+			resourcesBatchSize,
+			resourcesOffset,
+		); err != nil {
+			return nil, err
+		}
+
+		if resourcesTotal < 1 {
+			uris = append(uris, source.CanonicalURI)
+		}
+
+		if len(resources) < 1 {
+			break
+		}
+
+		for _, resource := range resources {
+			uris = append(uris, fmt.Sprintf("%s%s", source.CanonicalURI, resource.CanonicalURI()))
+		}
+
+		resourcesOffset += resourcesBatchSize
+	}
+
+	return
+}
+
+func (context *Context) UpdateSourceWeight(source *Source, weight float64) (err error) {
+	if err = validateWeight(weight); err != nil {
+		return
+	}
+
+	if err = context.engine.database.Query(ColSources).UpdateById(source.ID, map[string]interface{}{
+		"weight": weight,
+	}); err != nil {
+		return
+	}
+
+	source.Weight = weight
+
+	context.engine.events.PublishSource(protocol.EventType_SOURCE_UPDATED, source)
+	return
+}
+
+func (context *Context) DeleteSource(source *Source) (resourcesPurged int, err error) {
+	var (
+		documents []*clover.Document
+		query     = context.engine.database.Query(ColResources).Where(
+			clover.Field("contextId").Eq(context.ID).And(clover.Field("sourceId").Eq(source.ID)),
+		)
+	)
+
+	context.engine.unwatchSource(source.ID)
+	context.engine.cancelJobs(context.engine.getSourceJobs(source))
+
+	if documents, err = query.FindAll(); err != nil {
+		return
+	}
+
+	batch := context.index.NewBatch()
+	batch.Delete(source.ID)
+
+	for _, document := range documents {
+		batch.Delete(document.ObjectId())
+	}
+
+	if err = context.index.Batch(batch); err != nil {
+		return
+	}
+
+	for _, document := range documents {
+		if err = context.engine.database.Query(ColResources).DeleteById(document.ObjectId()); err != nil {
+			return
+		}
+	}
+
+	if err = context.engine.database.Query(ColSources).DeleteById(source.ID); err != nil {
+		return
+	}
+
+	for _, document := range documents {
+		context.engine.events.PublishResourceRemoved(context.ID, document.ObjectId())
+	}
+
+	context.engine.events.PublishSource(protocol.EventType_SOURCE_REMOVED, source)
+
+	resourcesPurged = len(documents)
+	return
+}
+
 func (context *Context) GetResource(resourceID string) (resource Resource, err error) {
 	var (
 		document           *clover.Document
@@ -193,6 +506,11 @@ func (context *Context) GetResource(resourceID string) (resource Resource, err e
 		return
 	}
 
+	// the resources of other contexts are not found in this one
+	if document == nil || document.Get("contextId") != context.ID {
+		return nil, fmt.Errorf("%w: '%s'", errResourceNotFound, resourceID)
+	}
+
 	if resource, err = UnmarshalResource(document); err != nil {
 		return
 	}
@@ -235,6 +553,8 @@ func (context *Context) GetResourcesByCriteria(criteria *clover.Criteria, limit,
 			return nil, 0, err
 		}
 
+		resource.SetID(document.ObjectId())
+
 		resources = append(resources, resource)
 	}
 
@@ -245,68 +565,287 @@ func (context *Context) GetResources(limit, offset int) (resources []Resource, t
 	return context.GetResourcesByCriteria(nil, limit, offset)
 }
 
-func (context *Context) Search(queryString string, highlightStyle string) (result *SearchResult, err error) {
+func (context *Context) Search(searchQuery query.Query, options *SearchOptions) (result *SearchResult, err error) {
+	return SearchContexts([]*Context{context}, searchQuery, options)
+}
+
+// SearchContexts runs one search over the indexes of several contexts; the
+// index alias pages, sorts and counts the hits of every index as a whole
+func SearchContexts(contexts []*Context, searchQuery query.Query, options *SearchOptions) (result *SearchResult, err error) {
 	var (
+		index         bleve.Index
 		searchRequest *bleve.SearchRequest
 		searchResult  *bleve.SearchResult
+		ranking       *searchRanking
+		now           = time.Now()
+		contextsByID  = map[string]*Context{}
 	)
 
-	queryString = fmt.Sprintf("%s +%s:%s", queryString, RecordTypeField, RecordResource)
+	if len(contexts) < 1 {
+		return nil, fmt.Errorf("no context to search")
+	}
+
+	for _, context := range contexts {
+		contextsByID[context.ID] = context
+	}
+
+	if len(contexts) == 1 {
+		index = contexts[0].index
+	} else {
+		indexAlias := bleve.NewIndexAlias()
+
+		for _, context := range contexts {
+			indexAlias.Add(context.index)
+		}
+
+		index = indexAlias
+	}
+
+	if options == nil {
+		options = &SearchOptions{}
+	}
+
+	if isRankedByScore(options.SortBy) {
+		if ranking, err = loadSearchRanking(contexts, options.Recency); err != nil {
+			return
+		}
+
+		if ranking.isEmpty() {
+			ranking = nil
+		}
+	}
+
+	// the record type is a filter of its own, since a required clause within
+	// a query string would make the other clauses optional
+	conjunctionQuery := bleve.NewConjunctionQuery(
+		searchQuery,
+		filterQuery(RecordTypeField, []string{string(RecordResource)}),
+	)
+
+	for field, values := range options.Filters {
+		var fieldFilterQuery query.Query
+
+		if fieldFilterQuery, err = facetFilterQuery(field, values, now); err != nil {
+			return
+		}
+
+		conjunctionQuery.AddQuery(fieldFilterQuery)
+	}
+
+	// the pages beyond the rescore window keep the order of the index, the
+	// window being the same whatever the page, so that paging is stable
+	if options.offset() >= rescoreWindow {
+		ranking = nil
+	}
+
+	if ranking == nil {
+		searchRequest = bleve.NewSearchRequestOptions(conjunctionQuery, options.limit(), options.offset(), false)
+	} else {
+		size := options.offset() + options.limit()
+
+		if size < rescoreWindow {
+			size = rescoreWindow
+		}
+
+		searchRequest = bleve.NewSearchRequestOptions(conjunctionQuery, size, 0, false)
+		searchRequest.Fields = ranking.fields()
+	}
+
+	for field, facet := range options.Facets {
+		facetRequest := bleve.NewFacetRequest(queryFacetFields[field], facet.Size)
 
-	query := bleve.NewQueryStringQuery(queryString)
+		if dateFacetFields[field] {
+			for name, dateRange := range dateHistogram(facet.Interval, facet.Size, now) {
+				facetRequest.AddDateTimeRange(name, dateRange.Start, dateRange.End)
+			}
+		}
+
+		searchRequest.AddFacet(field.String(), facetRequest)
+	}
 
-	searchRequest = bleve.NewSearchRequest(query)
+	// ties in score are broken by document ID, so that pages stay stable
+	if len(options.SortBy) > 0 {
+		searchRequest.SortBy(options.SortBy)
+	} else {
+		searchRequest.SortBy([]string{"-_score", "_id"})
+	}
 
-	if highlightStyle != "" {
-		searchRequest.Highlight = bleve.NewHighlightWithStyle(highlightStyle)
+	searchRequest.Explain = options.Explain
 
-		searchRequest.Highlight.AddField(fmt.Sprintf("%s.contents_text", ResFSFile))
-		searchRequest.Highlight.AddField(fmt.Sprintf("%s.contents_html", ResFSFile))
+	if options.HighlightStyle != "" {
+		searchRequest.Highlight = bleve.NewHighlightWithStyle(options.HighlightStyle)
 
-		searchRequest.Highlight.AddField(fmt.Sprintf("%s.title", ResWebPage))
-		searchRequest.Highlight.AddField(fmt.Sprintf("%s.body", ResWebPage))
+		searchRequest.Highlight.AddField(RecordTitleField)
+		searchRequest.Highlight.AddField(RecordContentField)
 	}
 
-	if searchResult, err = context.index.Search(searchRequest); err != nil {
+	if searchResult, err = index.Search(searchRequest); err != nil {
 		return
 	}
 
+	// an alias reports the indexes failing to be searched apart, a closed one
+	// fails the search as the single index of a deleted context would
+	for _, indexErr := range searchResult.Status.Errors {
+		if errors.Is(indexErr, bleve.ErrorIndexClosed) {
+			return nil, indexErr
+		}
+	}
+
 	result = &SearchResult{
 		MaxScore: searchResult.MaxScore,
+		Total:    searchResult.Total,
+		Took:     searchResult.Took,
 		Hits:     make([]*SearchResultHit, 0),
+		Facets:   searchResult.Facets,
 	}
 
 	for _, hit := range searchResult.Hits {
+		context, hasContext := contextsByID[hit.Index]
+		if !hasContext {
+			continue
+		}
+
 		searchResultHit := &SearchResultHit{
-			Score:      hit.Score,
-			Highlights: hit.Fragments,
+			ContextID:   context.ID,
+			Score:       hit.Score,
+			Highlights:  hit.Fragments,
+			Explanation: hit.Expl,
+			fields:      hit.Fields,
 		}
 
-		if searchResultHit.Resource, err = context.GetResource(hit.ID); err != nil {
+		// the resource may have been unsourced since the index was searched
+		if searchResultHit.Resource, err = context.GetResource(hit.ID); errors.Is(err, errResourceNotFound) {
+			err = nil
+			continue
+		} else if err != nil {
 			return
 		}
 
 		result.Hits = append(result.Hits, searchResultHit)
 	}
 
+	if ranking != nil {
+		ranking.rescore(result, options.offset(), options.limit())
+	}
+
 	return
 }
 
-func (context *Context) initializeIndex() (err error) {
+// initializeIndex opens, or creates, the context's index and names it after the
+// context, the name tagging the hits of searches over several contexts; an
+// index built by an older mapping is rebuilt empty, its sources are then to
+// be indexed again
+func (context *Context) initializeIndex() (isRebuilt bool, err error) {
+	var mappingVersion []byte
+
 	context.index, err = bleve.Open(context.GetIndexPath())
 
 	if err == bleve.ErrorIndexPathDoesNotExist {
-		err = nil
+		return false, context.createIndex()
+	}
 
-		indexMapping, err := BuildIndexMapping()
-		if err != nil {
-			return err
-		}
+	if err != nil {
+		return
+	}
 
-		if context.index, err = bleve.New(context.GetIndexPath(), indexMapping); err != nil {
-			return err
-		}
+	context.index.SetName(context.ID)
+
+	if mappingVersion, err = context.index.GetInternal(indexMappingVersionKey); err != nil {
+		return
+	}
+
+	if string(mappingVersion) == strconv.Itoa(indexMappingVersion) {
+		return
+	}
+
+	return true, context.rebuildIndex()
+}
+
+func (context *Context) createIndex() (err error) {
+	var indexMapping *mapping.IndexMappingImpl
+
+	if indexMapping, err = BuildIndexMapping(); err != nil {
+		return
 	}
 
+	if context.index, err = bleve.New(context.GetIndexPath(), indexMapping); err != nil {
+		return
+	}
+
+	context.index.SetName(context.ID)
+
+	return context.index.SetInternal(indexMappingVersionKey, []byte(strconv.Itoa(indexMappingVersion)))
+}
+
+// rebuildIndex replaces the index with an empty one of the current mapping,
+// holding only the records of the sources
+func (context *Context) rebuildIndex() (err error) {
+	fmt.Printf("Rebuilding the index of context '%s' for mapping version %d\n", context.Name, indexMappingVersion)
+
+	if err = context.index.Close(); err != nil {
+		return
+	}
+
+	if err = os.RemoveAll(context.GetIndexPath()); err != nil {
+		return
+	}
+
+	if err = context.createIndex(); err != nil {
+		return
+	}
+
+	// the files without a hash are read again rather than skipped as
+	// unchanged; their size is kept, as it is counted in the bytes indexed
+	if err = context.engine.database.Query(ColResources).Where(
+		clover.Field("contextId").Eq(context.ID).And(clover.Field("type").Eq(string(ResFSFile))),
+	).Update(map[string]interface{}{
+		fmt.Sprintf("%s.hash", ResFSFile): "",
+	}); err != nil {
+		return
+	}
+
+	return context.eachSource(nil, func(source *Source) error {
+		record := make(Record).
+			SetType(RecordSource).
+			SetAll(source.MarshalMap())
+
+		return context.index.Index(source.ID, record)
+	})
+}
+
+// closeIndex keeps the closed index around, so that RPCs still holding the
+// context get bleve.ErrorIndexClosed rather than a nil index
+func (context *Context) closeIndex() (err error) {
+	context.mutex.Lock()
+	defer context.mutex.Unlock()
+
+	if context.index == nil || context.isIndexClosed {
+		return
+	}
+
+	err = context.index.Close()
+	context.isIndexClosed = true
 	return
 }
+
+func (context *Context) updateSourceStats(source *Source, indexErr error) (err error) {
+	var resourcesTotal int
+
+	if resourcesTotal, err = context.engine.database.Query(ColResources).Where(
+		clover.Field("contextId").Eq(context.ID).And(clover.Field("sourceId").Eq(source.ID)),
+	).Count(); err != nil {
+		return
+	}
+
+	source.Stats.ResourcesTotal = int64(resourcesTotal)
+	source.Stats.LastIndexedAt = time.Now()
+	source.Stats.LastError = ""
+
+	if indexErr != nil {
+		source.Stats.LastError = indexErr.Error()
+	}
+
+	return context.engine.database.Query(ColSources).UpdateById(source.ID, map[string]interface{}{
+		"stats": source.Stats.MarshalMap(),
+	})
+}
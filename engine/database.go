@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"sync"
+
+	"github.com/necessitates/clover"
+)
+
+/**
+ * URN locks : Serialize the "find by URN, else insert" of documents, so that
+ * concurrent crawls of overlapping URIs never insert the same URN twice
+ */
+
+type urnLock struct {
+	sync.Mutex
+	references int
+}
+
+var (
+	urnLocks      = map[string]*urnLock{}
+	urnLocksMutex sync.Mutex
+)
+
+func lockURN(urn string) (unlock func()) {
+	urnLocksMutex.Lock()
+	lock, hasLock := urnLocks[urn]
+	if !hasLock {
+		lock = &urnLock{}
+		urnLocks[urn] = lock
+	}
+	lock.references++
+	urnLocksMutex.Unlock()
+
+	lock.Lock()
+
+	return func() {
+		lock.Unlock()
+
+		urnLocksMutex.Lock()
+		lock.references--
+		if lock.references < 1 {
+			delete(urnLocks, urn)
+		}
+		urnLocksMutex.Unlock()
+	}
+}
+
+// findOrInsertByURN returns the document of the given URN, inserting the one
+// built by newDocument when there is none yet
+func findOrInsertByURN(
+	database *clover.DB,
+	collection string,
+	urn string,
+	newDocument func() (*clover.Document, error),
+) (document *clover.Document, isInserted bool, err error) {
+	unlock := lockURN(urn)
+	defer unlock()
+
+	if document, err = database.Query(collection).Where(
+		clover.Field("urn").Eq(urn),
+	).FindFirst(); err != nil || document != nil {
+		return
+	}
+
+	if document, err = newDocument(); err != nil {
+		return
+	}
+
+	if _, err = database.InsertOne(collection, document); err != nil {
+		return nil, false, err
+	}
+
+	isInserted = true
+	return
+}
+
+// deleteWhere deletes the documents matching the criteria one by one; unlike
+// clover's criteria deletes, which read the whole collection in the same
+// transaction, it does not conflict with the concurrent writes of other documents
+func deleteWhere(database *clover.DB, collection string, criteria *clover.Criteria) (deleted int, err error) {
+	var documents []*clover.Document
+
+	query := database.Query(collection)
+
+	if documents, err = query.Where(criteria).FindAll(); err != nil {
+		return
+	}
+
+	for _, document := range documents {
+		if err = query.DeleteById(document.ObjectId()); err != nil {
+			return
+		}
+
+		deleted++
+	}
+
+	return
+}
@@ -0,0 +1,203 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+
+	"risp/protocol"
+)
+
+// dateIntervalLayouts name the buckets of date histograms, and are the
+// layouts of the dates filters take
+var dateIntervalLayouts = map[protocol.QueryDateInterval]string{
+	protocol.QueryDateInterval_DAY:   "2006-01-02",
+	protocol.QueryDateInterval_MONTH: "2006-01",
+	protocol.QueryDateInterval_YEAR:  "2006",
+}
+
+/**
+ * DateRange : A span of time, from Start up to but excluding End; a zero
+ * Start or End leaves the range open on that side
+ */
+
+type DateRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (dateRange *DateRange) Query(field string) query.Query {
+	dateRangeQuery := bleve.NewDateRangeQuery(dateRange.Start, dateRange.End)
+	dateRangeQuery.SetField(field)
+
+	return dateRangeQuery
+}
+
+// ParseDateRange reads a date ("2024", "2024-05", "2024-05-17"), a relative
+// range ("today", "yesterday", "this week", "last month", "past 3 days") or
+// a range of dates ("2024-01..2024-03", "2024-05..", "..2023")
+func ParseDateRange(value string, now time.Time) (dateRange *DateRange, err error) {
+	bounds := strings.SplitN(value, "..", 2)
+
+	if len(bounds) < 2 {
+		return parseDatePeriod(value, now)
+	}
+
+	dateRange = &DateRange{}
+
+	if strings.TrimSpace(bounds[0]) != "" {
+		var startPeriod *DateRange
+
+		if startPeriod, err = parseDatePeriod(bounds[0], now); err != nil {
+			return nil, err
+		}
+
+		dateRange.Start = startPeriod.Start
+	}
+
+	if strings.TrimSpace(bounds[1]) != "" {
+		var endPeriod *DateRange
+
+		if endPeriod, err = parseDatePeriod(bounds[1], now); err != nil {
+			return nil, err
+		}
+
+		dateRange.End = endPeriod.End
+	}
+
+	if dateRange.Start.IsZero() && dateRange.End.IsZero() {
+		return nil, fmt.Errorf("invalid date range '%s': missing both start and end", value)
+	}
+
+	return
+}
+
+func parseDatePeriod(value string, now time.Time) (*DateRange, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	for _, interval := range []protocol.QueryDateInterval{
+		protocol.QueryDateInterval_DAY,
+		protocol.QueryDateInterval_MONTH,
+		protocol.QueryDateInterval_YEAR,
+	} {
+		if start, err := time.ParseInLocation(dateIntervalLayouts[interval], value, now.Location()); err == nil {
+			return &DateRange{
+				Start: start,
+				End:   addDateInterval(start, interval, 1),
+			}, nil
+		}
+	}
+
+	if len(value) > 0 && unicode.IsLetter(rune(value[0])) {
+		value = strings.NewReplacer("-", " ", "_", " ").Replace(value)
+	}
+
+	words := strings.Fields(value)
+	today := truncateDate(now, protocol.QueryDateInterval_DAY)
+
+	switch true {
+	case len(words) == 1 && words[0] == "today":
+		return &DateRange{Start: today, End: addDateInterval(today, protocol.QueryDateInterval_DAY, 1)}, nil
+	case len(words) == 1 && words[0] == "yesterday":
+		return &DateRange{Start: addDateInterval(today, protocol.QueryDateInterval_DAY, -1), End: today}, nil
+	case len(words) == 2 && words[0] == "this":
+		if interval, isWeek, isUnit := parseDateUnit(words[1]); isUnit {
+			start := truncateDate(now, interval)
+			end := addDateInterval(start, interval, 1)
+
+			// weeks start on monday
+			if isWeek {
+				start = today.AddDate(0, 0, -((int(now.Weekday()) + 6) % 7))
+				end = start.AddDate(0, 0, 7)
+			}
+
+			return &DateRange{Start: start, End: end}, nil
+		}
+	case (len(words) == 2 || len(words) == 3) && (words[0] == "last" || words[0] == "past"):
+		count := 1
+
+		if len(words) == 3 {
+			var err error
+
+			if count, err = strconv.Atoi(words[1]); err != nil || count < 1 {
+				break
+			}
+		}
+
+		if interval, isWeek, isUnit := parseDateUnit(words[len(words)-1]); isUnit {
+			start := addDateInterval(now, interval, -count)
+
+			if isWeek {
+				start = now.AddDate(0, 0, -7*count)
+			}
+
+			return &DateRange{Start: start}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid date '%s'", value)
+}
+
+// parseDateUnit reads "day", "week", "month" or "year", in singular or
+// plural; weeks, having no interval of their own, are told apart by isWeek
+func parseDateUnit(unit string) (interval protocol.QueryDateInterval, isWeek bool, isUnit bool) {
+	switch strings.TrimSuffix(unit, "s") {
+	case "day":
+		return protocol.QueryDateInterval_DAY, false, true
+	case "week":
+		return protocol.QueryDateInterval_DAY, true, true
+	case "month":
+		return protocol.QueryDateInterval_MONTH, false, true
+	case "year":
+		return protocol.QueryDateInterval_YEAR, false, true
+	}
+
+	return 0, false, false
+}
+
+func truncateDate(date time.Time, interval protocol.QueryDateInterval) time.Time {
+	switch interval {
+	case protocol.QueryDateInterval_DAY:
+		return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	case protocol.QueryDateInterval_YEAR:
+		return time.Date(date.Year(), 1, 1, 0, 0, 0, 0, date.Location())
+	}
+
+	return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+}
+
+func addDateInterval(date time.Time, interval protocol.QueryDateInterval, count int) time.Time {
+	switch interval {
+	case protocol.QueryDateInterval_DAY:
+		return date.AddDate(0, 0, count)
+	case protocol.QueryDateInterval_YEAR:
+		return date.AddDate(count, 0, 0)
+	}
+
+	return date.AddDate(0, count, 0)
+}
+
+// dateHistogram lists the given number of intervals, named after their
+// start, from the current one back in time
+func dateHistogram(interval protocol.QueryDateInterval, size int, now time.Time) (buckets map[string]*DateRange) {
+	buckets = map[string]*DateRange{}
+	end := addDateInterval(truncateDate(now, interval), interval, 1)
+
+	for i := 0; i < size; i++ {
+		start := addDateInterval(end, interval, -1)
+
+		buckets[start.Format(dateIntervalLayouts[interval])] = &DateRange{
+			Start: start,
+			End:   end,
+		}
+
+		end = start
+	}
+
+	return
+}
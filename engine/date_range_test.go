@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDateRange(t *testing.T) {
+	var (
+		friday = time.Date(2024, 5, 17, 15, 4, 5, 0, time.UTC)
+		sunday = time.Date(2024, 5, 19, 9, 0, 0, 0, time.UTC)
+		date   = func(year int, month time.Month, day int) time.Time {
+			return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		}
+	)
+
+	for _, test := range []struct {
+		value      string
+		now        time.Time
+		start, end time.Time
+		isError    bool
+	}{
+		{"2024", friday, date(2024, 1, 1), date(2025, 1, 1), false},
+		{"2024-02", friday, date(2024, 2, 1), date(2024, 3, 1), false},
+		{"2024-02-29", friday, date(2024, 2, 29), date(2024, 3, 1), false},
+		{"today", friday, date(2024, 5, 17), date(2024, 5, 18), false},
+		{"Yesterday", friday, date(2024, 5, 16), date(2024, 5, 17), false},
+		{"this week", friday, date(2024, 5, 13), date(2024, 5, 20), false},
+		{"this week", sunday, date(2024, 5, 13), date(2024, 5, 20), false},
+		{"this-month", friday, date(2024, 5, 1), date(2024, 6, 1), false},
+		{"this year", friday, date(2024, 1, 1), date(2025, 1, 1), false},
+		{"last day", friday, friday.AddDate(0, 0, -1), time.Time{}, false},
+		{"last week", friday, friday.AddDate(0, 0, -7), time.Time{}, false},
+		{"past 3 days", friday, friday.AddDate(0, 0, -3), time.Time{}, false},
+		{"past 2 weeks", friday, friday.AddDate(0, 0, -14), time.Time{}, false},
+		{"last_6_months", friday, friday.AddDate(0, -6, 0), time.Time{}, false},
+		{"past 1 year", friday, friday.AddDate(-1, 0, 0), time.Time{}, false},
+		{"2024-01..2024-03", friday, date(2024, 1, 1), date(2024, 4, 1), false},
+		{"2024-05..", friday, date(2024, 5, 1), time.Time{}, false},
+		{"..2023", friday, time.Time{}, date(2024, 1, 1), false},
+		{"last month..today", friday, friday.AddDate(0, -1, 0), date(2024, 5, 18), false},
+		{"..", friday, time.Time{}, time.Time{}, true},
+		{"past 0 days", friday, time.Time{}, time.Time{}, true},
+		{"this fortnight", friday, time.Time{}, time.Time{}, true},
+		{"2024-13", friday, time.Time{}, time.Time{}, true},
+		{"2024-01..someday", friday, time.Time{}, time.Time{}, true},
+	} {
+		dateRange, err := ParseDateRange(test.value, test.now)
+
+		if (err != nil) != test.isError {
+			t.Errorf("parsing %q: error %v, want error %v", test.value, err, test.isError)
+			continue
+		}
+
+		if test.isError {
+			continue
+		}
+
+		if !dateRange.Start.Equal(test.start) || !dateRange.End.Equal(test.end) {
+			t.Errorf("parsing %q on %s: %s..%s, want %s..%s", test.value, test.now.Weekday(), dateRange.Start, dateRange.End, test.start, test.end)
+		}
+	}
+}
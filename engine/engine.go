@@ -2,12 +2,17 @@ package engine
 
 import (
 	_context "context"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
 	"github.com/necessitates/clover"
 	"google.golang.org/grpc"
 
@@ -17,26 +22,52 @@ import (
 )
 
 const (
-	ColContexts  string = "Contexts"
-	ColSources   string = "Sources"
-	ColResources string = "Resources"
+	ColContexts      string = "Contexts"
+	ColSources       string = "Sources"
+	ColResources     string = "Resources"
+	ColSavedQueries  string = "SavedQueries"
+	ColSearchHistory string = "SearchHistory"
 )
 
-var collections = []string{ColContexts, ColSources, ColResources}
+var collections = []string{ColContexts, ColSources, ColResources, ColSavedQueries, ColSearchHistory}
+
+const stopTimeout = 10 * time.Second
+
+const FieldObjectID string = "_id"
+
+var (
+	errDefaultContext  = errors.New("cannot delete the default context")
+	errContextNotFound = errors.New("context not found")
+)
 
 type Engine struct {
 	protocol.UnimplementedRispServer
-	config   *config.Config
-	database *clover.DB
-	contexts map[string]*Context
-	// stopSignal chan bool
+	config         *config.Config
+	database       *clover.DB
+	contexts       map[string]*Context
+	contextsMutex  sync.RWMutex
+	jobs           map[string]*Job
+	jobsMutex      sync.RWMutex
+	jobsGroup      sync.WaitGroup
+	watchers       map[string]*sourceWatcher
+	watchersMutex  sync.Mutex
+	watchersClosed bool
+	events         *EventBus
+	server         *grpc.Server
+	serverMutex    sync.Mutex
+	isStopping     bool // guarded by serverMutex, refuses to serve and to run jobs once set
+	stopOnce       sync.Once
+	stopped        chan struct{}
 }
 
 func NewEngine(config *config.Config) *Engine {
 	return &Engine{
 		config:   config,
 		contexts: map[string]*Context{},
-		// stopSignal: make(chan bool),
+		jobs:     map[string]*Job{},
+		watchers: map[string]*sourceWatcher{},
+		events:   NewEventBus(),
+		stopped:  make(chan struct{}),
 	}
 }
 
@@ -59,57 +90,121 @@ func (engine *Engine) Start() (err error) {
 		return
 	}
 
-	// <-engine.stopSignal
+	<-engine.stopped
 	return
 }
 
+// Stop drains the gRPC server, cancels running jobs and flushes every index
+// and the master database, forcing its way through once stopTimeout elapses
 func (engine *Engine) Stop() (err error) {
-	fmt.Printf("Engine stopping\n")
+	engine.stopOnce.Do(func() {
+		defer close(engine.stopped)
+
+		fmt.Printf("Engine stopping\n")
+
+		engine.serverMutex.Lock()
+		engine.isStopping = true
+		server := engine.server
+		engine.serverMutex.Unlock()
+
+		deadline := time.Now().Add(stopTimeout)
+
+		engine.closeWatchers()
+		engine.events.Close()
+
+		// the RPCs drained start no more jobs, the ones started are listed
+		if server != nil {
+			if !waitUntil(deadline, server.GracefulStop) {
+				fmt.Printf("  gRPC server did not drain in time, forcing stop\n")
+				server.Stop()
+			}
+		}
+
+		for _, job := range engine.getJobs("") {
+			job.Cancel()
+		}
+
+		if !waitUntil(deadline, engine.jobsGroup.Wait) {
+			fmt.Printf("  jobs did not finish in time\n")
+		}
+
+		for _, context := range engine.getContexts() {
+			if closeErr := context.closeIndex(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}
+
+		if engine.database != nil {
+			if closeErr := engine.database.Close(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}
+
+		fmt.Printf("Engine stopped\n")
+	})
 
-	// engine.stopSignal <- true
 	return
 }
 
 func (engine *Engine) Execute(context _context.Context, request *protocol.ExecuteRequest) (response *protocol.ExecuteResponse, err error) {
+	var words []string
+
 	response = &protocol.ExecuteResponse{
 		Error: NewProtocolError(),
 	}
 
 	fmt.Printf("Execute called\n")
+	fmt.Printf("  request.Command: '%s'\n", request.Command)
 
-	commands := strings.Split(request.Command, " ")
-	if len(commands) < 1 {
+	if words, err = parseCommandLine(request.Command); err != nil {
+		response.Error = NewProtocolError(ErrInvalidCommand, err)
+		err = nil
+		return
+	}
+
+	if len(words) < 1 {
 		response.Error = NewProtocolError(ErrInvalidCommand, "Empty Command")
 		return
 	}
 
-	fmt.Printf("  request.Command: '%s'\n", request.Command)
+	command, args := findCommand(words)
+	if command == nil {
+		response.Error = NewProtocolError(ErrInvalidCommand, fmt.Sprintf("Invalid Command: '%s'", words[0]))
+		return
+	}
 
-	switch commands[0] {
-	case "source":
-		if len(commands) < 2 || len(commands[1]) < 1 {
-			response.Error = NewProtocolError(ErrInvalidSourceURI, "Missing Source URI")
-			return
-		}
+	response.Command = command.Name
 
-		fmt.Printf("  sourcing '%s'\n", commands[1])
+	if len(args) < command.MinArgs || (command.MaxArgs >= 0 && len(args) > command.MaxArgs) {
+		response.Error = NewProtocolError(ErrInvalidCommand, fmt.Sprintf("Usage: %s", command.Usage))
+		return
+	}
 
-	resolveContext:
-		for _, context := range engine.contexts {
-			if context.IsDefault {
-				// var source Source
-				if _, err = context.SourceURI(commands[1]); err != nil {
-					return
-				}
+	call := &CommandCall{
+		RPCContext: context,
+		Args:       args,
+		Response:   response,
+	}
 
-				break resolveContext
-			}
+	if command.needsContext {
+		var hasContext bool
+
+		if call.Context, hasContext = engine.resolveContext(request.ContextId); !hasContext {
+			response.Error = NewProtocolError(ErrInvalidContext, "Context Not Found")
+			return
 		}
+	}
 
-		return
+	err = command.run(engine, call)
+	return
+}
+
+func (engine *Engine) ListCommands(context _context.Context, request *protocol.ListCommandsRequest) (response *protocol.ListCommandsResponse, err error) {
+	response = &protocol.ListCommandsResponse{
+		Error:    NewProtocolError(),
+		Commands: listCommands(),
 	}
 
-	response.Error = NewProtocolError(ErrInvalidCommand, fmt.Sprintf("Invalid Command: '%s'", commands[0]))
 	return
 }
 
@@ -120,8 +215,27 @@ func (engine *Engine) Query(context _context.Context, request *protocol.QueryReq
 
 	fmt.Printf("Query called\n")
 
-	if len(request.Value) < 1 {
-		response.Error = NewProtocolError(ErrInvalidQuery, "Empty Query")
+	var (
+		searchQuery   query.Query
+		searchFilters map[protocol.QueryFacetField][]string
+		searchRecency *SearchRecency
+	)
+
+	if searchQuery, err = UnmarshalSearchQuery(request); err != nil {
+		response.Error = NewProtocolError(ErrInvalidQuery, err)
+		err = nil
+		return
+	}
+
+	if searchFilters, err = UnmarshalQueryFilters(request.Filters); err != nil {
+		response.Error = NewProtocolError(ErrInvalidQuery, err)
+		err = nil
+		return
+	}
+
+	if searchRecency, err = UnmarshalQueryRecency(request.Recency); err != nil {
+		response.Error = NewProtocolError(ErrInvalidQuery, err)
+		err = nil
 		return
 	}
 
@@ -139,21 +253,289 @@ func (engine *Engine) Query(context _context.Context, request *protocol.QueryReq
 	fmt.Printf("  request.Value: '%s'\n", request.Value)
 	fmt.Printf("  request.HighlightStyle: '%s' (actual used: '%s')\n", request.HighlightStyle, highlightStyle)
 
-	for _, context := range engine.contexts {
-		if context.IsDefault {
-			var searchResult *SearchResult
+	targetContexts, missingContextID := engine.resolveQueryContexts(request)
+	if missingContextID != "" {
+		response.Error = NewProtocolError(ErrInvalidContext, fmt.Sprintf("Context Not Found: '%s'", missingContextID))
+		return
+	}
 
-			if searchResult, err = context.Search(request.Value, highlightStyle); err != nil {
-				return
-			}
+	if len(targetContexts) < 1 {
+		response.Error = NewProtocolError(ErrInvalidContext, "Context Not Found")
+		return
+	}
+
+	var searchResult *SearchResult
+
+	searchOptions := &SearchOptions{
+		HighlightStyle: highlightStyle,
+		Limit:          int(request.Limit),
+		Offset:         int(request.Offset),
+		SortBy:         UnmarshalQuerySort(request.Sort),
+		Facets:         UnmarshalQueryFacets(request.Facets),
+		Filters:        searchFilters,
+		Recency:        searchRecency,
+		Explain:        request.Explain,
+	}
+
+	if searchResult, err = SearchContexts(targetContexts, searchQuery, searchOptions); errors.Is(err, bleve.ErrorIndexClosed) {
+		// a context deleted while searched
+		response.Error = NewProtocolError(ErrInvalidContext, "Context Not Found")
+		err = nil
+		return
+	} else if err != nil {
+		return
+	}
+
+	fmt.Printf("  searchResult: %+v\n", searchResult)
+
+	response = searchResult.MarshalProtocol()
 
-			fmt.Printf("  searchResult: %+v\n", searchResult)
+	// the later pages of a search are the same search; a search failing to be
+	// recorded is reported, its hits still returned
+	if request.Offset < 1 {
+		if entry, recordErr := engine.recordSearch(targetContexts, request, searchResult.Total); recordErr != nil {
+			fmt.Printf("  failed to record the search: %+v\n", recordErr)
 
-			response = searchResult.MarshalProtocol()
-			break
+			for _, targetContext := range targetContexts {
+				engine.events.PublishError(targetContext.ID, recordErr)
+			}
+		} else {
+			response.SearchId = entry.ID
 		}
 	}
 
+	if request.Query == nil && searchResult.Total <= spellcheckMaxHits {
+		if response.DidYouMean, err = CorrectQueryString(targetContexts, request.Value, searchResult.Total, searchOptions); errors.Is(err, bleve.ErrorIndexClosed) {
+			response.Error = NewProtocolError(ErrInvalidContext, "Context Not Found")
+			err = nil
+		} else if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (engine *Engine) Suggest(context _context.Context, request *protocol.SuggestRequest) (response *protocol.SuggestResponse, err error) {
+	var suggestions []*Suggestion
+
+	response = &protocol.SuggestResponse{
+		Error:       NewProtocolError(),
+		Suggestions: make([]*protocol.QuerySuggestion, 0),
+	}
+
+	startedAt := time.Now()
+
+	targetContext, hasContext := engine.resolveContext(request.ContextId)
+	if !hasContext {
+		response.Error = NewProtocolError(ErrInvalidContext, "Context Not Found")
+		return
+	}
+
+	if suggestions, err = targetContext.Suggest(request.Value, int(request.Limit)); err != nil {
+		return
+	}
+
+	for _, suggestion := range suggestions {
+		response.Suggestions = append(response.Suggestions, suggestion.MarshalProtocol())
+	}
+
+	response.Took = time.Since(startedAt).Milliseconds()
+	return
+}
+
+func (engine *Engine) SaveQuery(context _context.Context, request *protocol.SaveQueryRequest) (response *protocol.SaveQueryResponse, err error) {
+	var (
+		savedQuery    *SavedQuery
+		searchFilters map[protocol.QueryFacetField][]string
+	)
+
+	response = &protocol.SaveQueryResponse{
+		Error: NewProtocolError(),
+	}
+
+	if len(strings.TrimSpace(request.Name)) < 1 {
+		response.Error = NewProtocolError(ErrInvalidSavedQuery, "Missing Saved Query Name")
+		return
+	}
+
+	if _, err = UnmarshalSearchQuery(&protocol.QueryRequest{Value: request.Value}); err != nil {
+		response.Error = NewProtocolError(ErrInvalidQuery, err)
+		err = nil
+		return
+	}
+
+	if searchFilters, err = UnmarshalQueryFilters(request.Filters); err != nil {
+		response.Error = NewProtocolError(ErrInvalidQuery, err)
+		err = nil
+		return
+	}
+
+	targetContext, hasContext := engine.resolveContext(request.ContextId)
+	if !hasContext {
+		response.Error = NewProtocolError(ErrInvalidContext, "Context Not Found")
+		return
+	}
+
+	if savedQuery, err = targetContext.SaveQuery(strings.TrimSpace(request.Name), request.Value, searchFilters); err != nil {
+		return
+	}
+
+	response.SavedQuery = savedQuery.MarshalProtocol()
+	return
+}
+
+func (engine *Engine) ListSavedQueries(context _context.Context, request *protocol.ListSavedQueriesRequest) (response *protocol.ListSavedQueriesResponse, err error) {
+	var savedQueries []*SavedQuery
+
+	response = &protocol.ListSavedQueriesResponse{
+		Error:        NewProtocolError(),
+		SavedQueries: make([]*protocol.SavedQuery, 0),
+	}
+
+	targetContext, hasContext := engine.resolveContext(request.ContextId)
+	if !hasContext {
+		response.Error = NewProtocolError(ErrInvalidContext, "Context Not Found")
+		return
+	}
+
+	if savedQueries, err = targetContext.GetSavedQueries(); err != nil {
+		return
+	}
+
+	for _, savedQuery := range savedQueries {
+		response.SavedQueries = append(response.SavedQueries, savedQuery.MarshalProtocol())
+	}
+
+	return
+}
+
+func (engine *Engine) RunSavedQuery(context _context.Context, request *protocol.RunSavedQueryRequest) (response *protocol.RunSavedQueryResponse, err error) {
+	var (
+		savedQuery     *SavedQuery
+		searchResult   *SearchResult
+		newResourceIDs []string
+	)
+
+	response = &protocol.RunSavedQueryResponse{
+		Error: NewProtocolError(),
+		Edges: make([]*protocol.QueryHit, 0),
+	}
+
+	if len(request.Id) < 1 {
+		response.Error = NewProtocolError(ErrInvalidSavedQuery, "Missing Saved Query ID or Name")
+		return
+	}
+
+	targetContext, hasContext := engine.resolveContext(request.ContextId)
+	if !hasContext {
+		response.Error = NewProtocolError(ErrInvalidContext, "Context Not Found")
+		return
+	}
+
+	if savedQuery, searchResult, newResourceIDs, err = targetContext.RunSavedQuery(request.Id); errors.Is(err, errSavedQueryNotFound) {
+		response.Error = NewProtocolError(ErrInvalidSavedQuery, err)
+		err = nil
+		return
+	} else if err != nil {
+		return
+	}
+
+	if limit := (&SearchOptions{Limit: int(request.Limit)}).limit(); len(searchResult.Hits) > limit {
+		searchResult.Hits = searchResult.Hits[:limit]
+	}
+
+	queryResponse := searchResult.MarshalProtocol()
+
+	response.SavedQuery = savedQuery.MarshalProtocol()
+	response.EdgesTotal = queryResponse.EdgesTotal
+	response.Edges = queryResponse.Edges
+	response.NewResourceIds = newResourceIDs
+	response.Took = queryResponse.Took
+	return
+}
+
+func (engine *Engine) GetSearchHistory(context _context.Context, request *protocol.GetSearchHistoryRequest) (response *protocol.GetSearchHistoryResponse, err error) {
+	var (
+		limit        = defaultSearchHistoryLimit
+		offset       = 0
+		entries      []*SearchHistoryEntry
+		entriesTotal int
+	)
+
+	response = &protocol.GetSearchHistoryResponse{
+		Error:   NewProtocolError(),
+		Entries: make([]*protocol.SearchHistoryEntry, 0),
+	}
+
+	if request.Limit > 0 {
+		limit = int(request.Limit)
+	}
+
+	if request.Offset >= 0 {
+		offset = int(request.Offset)
+	}
+
+	targetContext, hasContext := engine.resolveContext(request.ContextId)
+	if !hasContext {
+		response.Error = NewProtocolError(ErrInvalidContext, "Context Not Found")
+		return
+	}
+
+	if entries, entriesTotal, err = targetContext.GetSearchHistory(limit, offset, request.ZeroHits); err != nil {
+		return
+	}
+
+	response.EntriesTotal = int64(entriesTotal)
+
+	for _, entry := range entries {
+		response.Entries = append(response.Entries, entry.MarshalProtocol())
+	}
+
+	return
+}
+
+func (engine *Engine) ClearSearchHistory(context _context.Context, request *protocol.ClearSearchHistoryRequest) (response *protocol.ClearSearchHistoryResponse, err error) {
+	var entriesCleared int
+
+	response = &protocol.ClearSearchHistoryResponse{
+		Error: NewProtocolError(),
+	}
+
+	targetContext, hasContext := engine.resolveContext(request.ContextId)
+	if !hasContext {
+		response.Error = NewProtocolError(ErrInvalidContext, "Context Not Found")
+		return
+	}
+
+	if entriesCleared, err = targetContext.ClearSearchHistory(); err != nil {
+		return
+	}
+
+	response.EntriesCleared = int64(entriesCleared)
+	return
+}
+
+func (engine *Engine) OpenSearchResult(context _context.Context, request *protocol.OpenSearchResultRequest) (response *protocol.OpenSearchResultResponse, err error) {
+	response = &protocol.OpenSearchResultResponse{
+		Error: NewProtocolError(),
+	}
+
+	if len(request.SearchId) < 1 {
+		response.Error = NewProtocolError(ErrInvalidSearch, "Missing Search ID")
+		return
+	}
+
+	if len(request.ResourceId) < 1 {
+		response.Error = NewProtocolError(ErrInvalidResource, "Missing Resource ID")
+		return
+	}
+
+	if err = engine.openSearchResult(request.SearchId, request.ResourceId); errors.Is(err, errSearchNotFound) {
+		response.Error = NewProtocolError(ErrInvalidSearch, err)
+		err = nil
+	}
+
 	return
 }
 
@@ -166,45 +548,154 @@ func (engine *Engine) IndexURI(context _context.Context, request *protocol.Index
 	fmt.Printf("  request.Context: %s\n", request.ContextId)
 	fmt.Printf("  request.Uri: '%s'\n", request.Uri)
 
-	for _, context := range engine.contexts {
-		// request.ContextId
-		if context.IsDefault {
-			var source *Source
-
-			if source, err = context.SourceURI(request.Uri); err != nil {
-				return
-			}
+	targetContext, hasContext := engine.resolveContext(request.ContextId)
+	if !hasContext {
+		response.Error = NewProtocolError(ErrInvalidContext, "Context Not Found")
+		return
+	}
 
-			response.Source = source.MarshalProtocol()
-		}
+	if len(request.Uri) < 1 {
+		response.Error = NewProtocolError(ErrInvalidSourceURI, "Missing Source URI")
+		return
 	}
 
+	job := engine.startJob(targetContext, request.Uri)
+
+	response.JobId = job.ID
+
 	fmt.Printf("  response: '%+v'\n", response)
 
 	return
 }
 
-func (engine *Engine) GetContext(context _context.Context, request *protocol.GetContextRequest) (response *protocol.GetContextResponse, err error) {
-	response = &protocol.GetContextResponse{
+func (engine *Engine) DeleteSource(context _context.Context, request *protocol.DeleteSourceRequest) (response *protocol.DeleteSourceResponse, err error) {
+	var (
+		source          *Source
+		resourcesPurged int
+	)
+
+	response = &protocol.DeleteSourceResponse{
 		Error: NewProtocolError(),
 	}
 
-	for contextID, context := range engine.contexts {
-		if (request.ContextId == nil || *request.ContextId == "") && context.IsDefault {
-			response.Context = context.MarshalProtocol()
-			break
-		}
+	fmt.Printf("Delete source called\n")
+	fmt.Printf("  request.Context: %s\n", request.ContextId)
+	fmt.Printf("  request.Source: '%s'\n", request.Source)
+
+	if len(request.Source) < 1 {
+		response.Error = NewProtocolError(ErrInvalidSource, "Missing Source URI or ID")
+		return
+	}
+
+	targetContext, hasContext := engine.resolveContext(request.ContextId)
+	if !hasContext {
+		response.Error = NewProtocolError(ErrInvalidContext, "Context Not Found")
+		return
+	}
+
+	if source, err = targetContext.FindSource(request.Source); err != nil {
+		response.Error = NewProtocolError(ErrInvalidSource, err)
+		err = nil
+		return
+	}
+
+	if resourcesPurged, err = targetContext.DeleteSource(source); err != nil {
+		return
+	}
+
+	response.ResourcesPurged = int64(resourcesPurged)
+	return
+}
+
+func (engine *Engine) GetJob(context _context.Context, request *protocol.GetJobRequest) (response *protocol.GetJobResponse, err error) {
+	response = &protocol.GetJobResponse{
+		Error: NewProtocolError(),
+	}
+
+	job, hasJob := engine.getJob(request.Id)
+	if !hasJob {
+		response.Error = NewProtocolError(ErrInvalidJob, "Job Not Found")
+		return
+	}
+
+	response.Job = job.MarshalProtocol()
+	return
+}
+
+func (engine *Engine) GetJobs(context _context.Context, request *protocol.GetJobsRequest) (response *protocol.GetJobsResponse, err error) {
+	response = &protocol.GetJobsResponse{
+		Error: NewProtocolError(),
+		Jobs:  make([]*protocol.Job, 0),
+	}
+
+	for _, job := range engine.getJobs(request.GetContextId()) {
+		response.Jobs = append(response.Jobs, job.MarshalProtocol())
+	}
+
+	sort.Slice(response.Jobs, func(a, b int) bool {
+		return response.Jobs[a].StartedAt > response.Jobs[b].StartedAt
+	})
+
+	response.JobsTotal = int64(len(response.Jobs))
+	return
+}
+
+func (engine *Engine) CancelJob(context _context.Context, request *protocol.CancelJobRequest) (response *protocol.CancelJobResponse, err error) {
+	response = &protocol.CancelJobResponse{
+		Error: NewProtocolError(),
+	}
+
+	fmt.Printf("Cancel job called\n")
+	fmt.Printf("  request.Id: %s\n", request.Id)
 
-		if (request.ContextId != nil && *request.ContextId != "") && contextID == *request.ContextId {
-			response.Context = context.MarshalProtocol()
-			break
+	job, hasJob := engine.getJob(request.Id)
+	if !hasJob {
+		response.Error = NewProtocolError(ErrInvalidJob, "Job Not Found")
+		return
+	}
+
+	job.Cancel()
+
+	response.Job = job.MarshalProtocol()
+	return
+}
+
+func (engine *Engine) Subscribe(request *protocol.SubscribeRequest, stream protocol.Risp_SubscribeServer) (err error) {
+	subscription := engine.events.Subscribe(request.ContextIds, request.Types)
+	defer engine.events.Unsubscribe(subscription)
+
+	fmt.Printf("Subscribe called\n")
+	fmt.Printf("  request.ContextIds: %+v\n", request.ContextIds)
+	fmt.Printf("  request.Types: %+v\n", request.Types)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, isOpen := <-subscription.Events:
+			if !isOpen {
+				return nil
+			}
+
+			if err = stream.Send(event); err != nil {
+				return
+			}
 		}
 	}
+}
 
-	if response.Context == nil {
+func (engine *Engine) GetContext(context _context.Context, request *protocol.GetContextRequest) (response *protocol.GetContextResponse, err error) {
+	response = &protocol.GetContextResponse{
+		Error: NewProtocolError(),
+	}
+
+	targetContext, hasContext := engine.resolveContext(request.GetContextId())
+	if !hasContext {
 		response.Error = NewProtocolError(ErrInvalidContext, "Context Not Found")
+		return
 	}
 
+	response.Context = targetContext.MarshalProtocol()
 	return
 }
 
@@ -213,25 +704,45 @@ func (engine *Engine) GetContexts(context _context.Context, request *protocol.Ge
 		Error: NewProtocolError(),
 	}
 
-	response.ContextsTotal = int64(len(engine.contexts))
+	contexts := engine.getContexts()
+
+	response.ContextsTotal = int64(len(contexts))
 	response.Contexts = make([]*protocol.Context, 0)
 
-	for _, context := range engine.contexts {
+	for _, context := range contexts {
 		response.Contexts = append(response.Contexts, context.MarshalProtocol())
 	}
 
 	return
 }
 
-// func (engine *Engine) GetSource(context _context.Context, request *protocol.GetSourceRequest) (response *protocol.GetSourceResponse, err error) {
-// 	response = &protocol.GetSourceResponse{
-// 		Error: NewProtocolError(),
-// 	}
+func (engine *Engine) GetSource(context _context.Context, request *protocol.GetSourceRequest) (response *protocol.GetSourceResponse, err error) {
+	var source *Source
+
+	response = &protocol.GetSourceResponse{
+		Error: NewProtocolError(),
+	}
+
+	if len(request.Id) < 1 {
+		response.Error = NewProtocolError(ErrInvalidSource, "Missing Source ID")
+		return
+	}
+
+	targetContext, hasContext := engine.resolveContext(request.ContextId)
+	if !hasContext {
+		response.Error = NewProtocolError(ErrInvalidContext, "Context Not Found")
+		return
+	}
 
-// 	// request.Id
+	if source, err = targetContext.GetSource(request.Id); err != nil {
+		response.Error = NewProtocolError(ErrInvalidSource, err)
+		err = nil
+		return
+	}
 
-// 	return
-// }
+	response.Source = source.MarshalProtocol()
+	return
+}
 
 func (engine *Engine) GetSources(context _context.Context, request *protocol.GetSourcesRequest) (response *protocol.GetSourcesResponse, err error) {
 	var (
@@ -251,42 +762,68 @@ func (engine *Engine) GetSources(context _context.Context, request *protocol.Get
 		offset = int(request.Offset)
 	}
 
+	targetContext, hasContext := engine.resolveContext(request.ContextId)
+	if !hasContext {
+		response.Error = NewProtocolError(ErrInvalidContext, "Context Not Found")
+		return
+	}
+
+	var (
+		sources      []*Source
+		sourcesTotal int
+	)
+
+	if sources, sourcesTotal, err = targetContext.GetSources(limit, offset); err != nil {
+		return
+	}
+
+	response.SourcesTotal = int64(sourcesTotal)
 	response.Sources = make([]*protocol.Source, 0)
 
-	for contextID, context := range engine.contexts {
-		if context.IsDefault {
-			var (
-				documents      []*clover.Document
-				documentsTotal int
-			)
+	for _, source := range sources {
+		response.Sources = append(response.Sources, source.MarshalProtocol())
+	}
 
-			query := context.engine.database.Query(ColSources).Where(clover.Field("contextId").Eq(contextID))
+	return
+}
 
-			if documentsTotal, err = query.Count(); err != nil {
-				return
-			}
+func (engine *Engine) UpdateSource(context _context.Context, request *protocol.UpdateSourceRequest) (response *protocol.UpdateSourceResponse, err error) {
+	var source *Source
 
-			response.SourcesTotal = int64(documentsTotal)
+	response = &protocol.UpdateSourceResponse{
+		Error: NewProtocolError(),
+	}
 
-			if documents, err = query.Limit(limit).Skip(offset).FindAll(); err != nil {
-				return
-			}
+	if len(request.Id) < 1 {
+		response.Error = NewProtocolError(ErrInvalidSource, "Missing Source ID")
+		return
+	}
 
-			for _, document := range documents {
-				source := &Source{
-					ContextID: contextID,
-					ID:        document.ObjectId(),
-				}
+	targetContext, hasContext := engine.resolveContext(request.ContextId)
+	if !hasContext {
+		response.Error = NewProtocolError(ErrInvalidContext, "Context Not Found")
+		return
+	}
 
-				if err = source.UnmarshalDBDocument(document); err != nil {
-					return
-				}
+	if source, err = targetContext.GetSource(request.Id); err != nil {
+		response.Error = NewProtocolError(ErrInvalidSource, err)
+		err = nil
+		return
+	}
 
-				response.Sources = append(response.Sources, source.MarshalProtocol())
-			}
+	if request.Weight != nil {
+		if err = validateWeight(*request.Weight); err != nil {
+			response.Error = NewProtocolError(ErrInvalidSource, err)
+			err = nil
+			return
+		}
+
+		if err = targetContext.UpdateSourceWeight(source, *request.Weight); err != nil {
+			return
 		}
 	}
 
+	response.Source = source.MarshalProtocol()
 	return
 }
 
@@ -308,41 +845,69 @@ func (engine *Engine) GetResources(context _context.Context, request *protocol.G
 		offset = int(request.Offset)
 	}
 
-	response.Resources = make([]*protocol.Resource, 0)
+	targetContext, hasContext := engine.resolveContext(request.ContextId)
+	if !hasContext {
+		response.Error = NewProtocolError(ErrInvalidContext, "Context Not Found")
+		return
+	}
 
-	for contextID, context := range engine.contexts {
-		if context.IsDefault {
-			var (
-				documents      []*clover.Document
-				documentsTotal int
-			)
+	var (
+		resources      []Resource
+		resourcesTotal int
+	)
 
-			query := context.engine.database.Query(ColResources).Where(clover.Field("contextId").Eq(contextID))
+	if resources, resourcesTotal, err = targetContext.GetResources(limit, offset); err != nil {
+		return
+	}
 
-			if documentsTotal, err = query.Count(); err != nil {
-				return
-			}
+	response.ResourcesTotal = int64(resourcesTotal)
+	response.Resources = make([]*protocol.Resource, 0)
 
-			response.ResourcesTotal = int64(documentsTotal)
+	for _, resource := range resources {
+		response.Resources = append(response.Resources, resource.MarshalProtocol())
+	}
 
-			if documents, err = query.Limit(limit).Skip(offset).FindAll(); err != nil {
-				return
-			}
+	return
+}
 
-			for _, document := range documents {
-				var resource Resource
+func (engine *Engine) GetRelatedResources(context _context.Context, request *protocol.GetRelatedResourcesRequest) (response *protocol.GetRelatedResourcesResponse, err error) {
+	var (
+		searchResult *SearchResult
+		terms        []string
+	)
 
-				if resource, err = UnmarshalResource(document); err != nil {
-					return
-				}
+	response = &protocol.GetRelatedResourcesResponse{
+		Error:     NewProtocolError(),
+		Resources: make([]*protocol.RelatedResource, 0),
+	}
 
-				resource.SetID(document.ObjectId())
+	if len(request.Id) < 1 {
+		response.Error = NewProtocolError(ErrInvalidResource, "Missing Resource ID")
+		return
+	}
 
-				response.Resources = append(response.Resources, resource.MarshalProtocol())
-			}
-		}
+	targetContext, hasContext := engine.resolveContext(request.ContextId)
+	if !hasContext {
+		response.Error = NewProtocolError(ErrInvalidContext, "Context Not Found")
+		return
 	}
 
+	if searchResult, terms, err = targetContext.GetRelatedResources(request.Id, int(request.Limit)); errors.Is(err, errResourceNotFound) {
+		response.Error = NewProtocolError(ErrInvalidResource, err)
+		err = nil
+		return
+	} else if err != nil {
+		return
+	}
+
+	for _, hit := range searchResult.Hits {
+		response.Resources = append(response.Resources, &protocol.RelatedResource{
+			Score:    float32(hit.Score),
+			Resource: hit.Resource.MarshalProtocol(),
+		})
+	}
+
+	response.Terms = terms
 	return
 }
 
@@ -353,13 +918,148 @@ func (engine *Engine) CreateContext(context _context.Context, request *protocol.
 		Error: NewProtocolError(),
 	}
 
-	if createdContext, err = engine.createContext(&Context{
-		Name: request.Name,
-	}); err != nil {
+	if createdContext, err = engine.createContext(&Context{
+		Name: request.Name,
+	}); err != nil {
+		return
+	}
+
+	response.Context = createdContext.MarshalProtocol()
+	return
+}
+
+func (engine *Engine) UpdateContext(context _context.Context, request *protocol.UpdateContextRequest) (response *protocol.UpdateContextResponse, err error) {
+	response = &protocol.UpdateContextResponse{
+		Error: NewProtocolError(),
+	}
+
+	targetContext, hasContext := engine.resolveContext(request.ContextId)
+	if !hasContext {
+		response.Error = NewProtocolError(ErrInvalidContext, "Context Not Found")
+		return
+	}
+
+	if request.Name != nil {
+		if len(*request.Name) < 1 {
+			response.Error = NewProtocolError(ErrInvalidContext, "Empty Context Name")
+			return
+		}
+
+		if err = engine.database.Query(ColContexts).UpdateById(targetContext.ID, map[string]interface{}{
+			"name": *request.Name,
+		}); err != nil {
+			return
+		}
+
+		targetContext.setName(*request.Name)
+	}
+
+	if request.Weight != nil {
+		if err = validateWeight(*request.Weight); err != nil {
+			response.Error = NewProtocolError(ErrInvalidContext, err)
+			err = nil
+			return
+		}
+
+		if err = engine.setContextWeight(targetContext, *request.Weight); err != nil {
+			return
+		}
+	}
+
+	if request.IsDefault != nil {
+		if !*request.IsDefault && targetContext.getIsDefault() {
+			response.Error = NewProtocolError(ErrInvalidContext, "Cannot unset the default context, set another context as default instead")
+			return
+		}
+
+		if *request.IsDefault && !targetContext.getIsDefault() {
+			if err = engine.setDefaultContext(targetContext); err != nil {
+				return
+			}
+		}
+	}
+
+	engine.events.PublishContext(protocol.EventType_CONTEXT_UPDATED, targetContext)
+
+	response.Context = targetContext.MarshalProtocol()
+	return
+}
+
+func (engine *Engine) DeleteContext(context _context.Context, request *protocol.DeleteContextRequest) (response *protocol.DeleteContextResponse, err error) {
+	response = &protocol.DeleteContextResponse{
+		Error: NewProtocolError(),
+	}
+
+	fmt.Printf("Delete context called\n")
+	fmt.Printf("  request.ContextId: %s\n", request.ContextId)
+
+	if len(request.ContextId) < 1 {
+		response.Error = NewProtocolError(ErrInvalidContext, "Missing Context ID")
+		return
+	}
+
+	targetContext, hasContext := engine.resolveContext(request.ContextId)
+	if !hasContext {
+		response.Error = NewProtocolError(ErrInvalidContext, "Context Not Found")
+		return
+	}
+
+	if targetContext.getIsDefault() {
+		response.Error = NewProtocolError(ErrInvalidContext, "Cannot delete the default context, set another context as default first")
+		return
+	}
+
+	if err = engine.deleteContext(targetContext); err == errDefaultContext {
+		response.Error = NewProtocolError(ErrInvalidContext, "Cannot delete the default context, set another context as default first")
+		err = nil
+	} else if err == errContextNotFound {
+		response.Error = NewProtocolError(ErrInvalidContext, "Context Not Found")
+		err = nil
+	}
+
+	return
+}
+
+func (engine *Engine) CloneContext(context _context.Context, request *protocol.CloneContextRequest) (response *protocol.CloneContextResponse, err error) {
+	var clonedContext *Context
+
+	response = &protocol.CloneContextResponse{
+		Error:  NewProtocolError(),
+		JobIds: make([]string, 0),
+	}
+
+	fmt.Printf("Clone context called\n")
+	fmt.Printf("  request.ContextId: %s\n", request.ContextId)
+	fmt.Printf("  request.Name: '%s'\n", request.Name)
+
+	targetContext, hasContext := engine.resolveContext(request.ContextId)
+	if !hasContext {
+		response.Error = NewProtocolError(ErrInvalidContext, "Context Not Found")
+		return
+	}
+
+	name := request.Name
+	if len(name) < 1 {
+		name = fmt.Sprintf("%s (copy)", targetContext.getName())
+	}
+
+	if clonedContext, err = engine.createContext(&Context{
+		Name: name,
+	}); err != nil {
+		return
+	}
+
+	var jobs []*Job
+
+	if jobs, err = engine.reindexSources(targetContext, clonedContext); err != nil {
 		return
 	}
 
-	response.Context = createdContext.MarshalProtocol()
+	for _, job := range jobs {
+		response.JobIds = append(response.JobIds, job.ID)
+	}
+
+	response.Context = clonedContext.MarshalProtocol()
 	return
 }
 
@@ -379,22 +1079,28 @@ func (engine *Engine) ExportContexts(context _context.Context, request *protocol
 	fmt.Printf("  request.ContextIds: %+v\n", request.ContextIds)
 
 	for _, contextID := range request.ContextIds {
-		if engine.contexts[contextID] == nil {
+		exportedContext, hasContext := engine.getContext(contextID)
+		if !hasContext {
 			continue
 		}
 
 		contextYAML := &dump.ContextYAML{
-			Name:      engine.contexts[contextID].Name,
-			IsDefault: engine.contexts[contextID].IsDefault,
+			ID:        contextID,
+			Name:      exportedContext.getName(),
+			IsDefault: exportedContext.getIsDefault(),
 			Sources:   make([]*dump.SourceYAML, 0),
 		}
 
+		if weight := exportedContext.getWeight(); weight != defaultWeight {
+			contextYAML.Weight = weight
+		}
+
 		sourcesOffset := 0
 		sourcesBatchSize := 100
 		for {
 			var sources = make([]*Source, 0)
 
-			if sources, _, err = engine.contexts[contextID].GetSources(sourcesBatchSize, sourcesOffset); err != nil {
+			if sources, _, err = exportedContext.GetSources(sourcesBatchSize, sourcesOffset); err != nil {
 				return nil, err
 			}
 
@@ -404,10 +1110,15 @@ func (engine *Engine) ExportContexts(context _context.Context, request *protocol
 
 			for _, source := range sources {
 				sourceYAML := &dump.SourceYAML{
+					ID:        source.ID,
 					URI:       source.CanonicalURI,
 					Resources: make(dump.Resources, 0),
 				}
 
+				if weight := source.weight(); weight != defaultWeight {
+					sourceYAML.Weight = weight
+				}
+
 				if true { // if source.AdapterType != AdapterTypeFS {
 					var (
 						resourcesOffset    = 0
@@ -418,7 +1129,7 @@ func (engine *Engine) ExportContexts(context _context.Context, request *protocol
 					for {
 						var resources = make([]Resource, 0)
 
-						if resources, _, err = engine.contexts[contextID].GetResourcesByCriteria(
+						if resources, _, err = exportedContext.GetResourcesByCriteria(
 							clover.Field("sourceId").Eq(source.ID),
 							resourcesBatchSize,
 							resourcesOffset,
@@ -464,6 +1175,134 @@ func (engine *Engine) ExportContexts(context _context.Context, request *protocol
 	return
 }
 
+func (engine *Engine) ImportContexts(context _context.Context, request *protocol.ImportContextsRequest) (response *protocol.ImportContextsResponse, err error) {
+	var (
+		contextsYAMLData []byte
+		data             *dump.DataYAML
+	)
+
+	response = &protocol.ImportContextsResponse{
+		Error:    NewProtocolError(),
+		Contexts: make([]*protocol.Context, 0),
+		JobIds:   make([]string, 0),
+	}
+
+	fmt.Printf("Import contexts called\n")
+	fmt.Printf("  request.InputPath: %+v\n", request.InputPath)
+
+	if contextsYAMLData, err = os.ReadFile(request.InputPath); err != nil {
+		return
+	}
+
+	if data, err = dump.DecodeDataYAML(contextsYAMLData); err != nil {
+		response.Error = NewProtocolError(ErrInvalidDump, err)
+		err = nil
+		return
+	}
+
+	// the whole dump is checked before anything of it is imported
+	if err = validateDataYAML(data); err != nil {
+		response.Error = NewProtocolError(ErrInvalidDump, err)
+		err = nil
+		return
+	}
+
+	// a context is imported into the local one of its ID when preserved, else
+	// of its name, and created otherwise; the local default context stays the
+	// default whatever the dump flags
+	importedContexts := make([]*Context, len(data.Contexts))
+
+	for i, contextYAML := range data.Contexts {
+		if data.PreserveContextID && contextYAML.ID != "" {
+			if importedContexts[i], _ = engine.getContext(contextYAML.ID); importedContexts[i] != nil {
+				continue
+			}
+		}
+
+		namesakeContext, hasNamesake := engine.findContextByName(contextYAML.Name)
+		if !hasNamesake {
+			continue
+		}
+
+		if data.PreserveContextID && contextYAML.ID != "" {
+			response.Error = NewProtocolError(ErrInvalidDump, fmt.Sprintf("Context Name Already Taken: '%s'", contextYAML.Name))
+			return
+		}
+
+		importedContexts[i] = namesakeContext
+	}
+
+	var targetContext *Context
+
+	if len(data.Sources) > 0 {
+		var hasContext bool
+
+		if targetContext, hasContext = engine.resolveContext(request.ContextId); !hasContext {
+			response.Error = NewProtocolError(ErrInvalidContext, "Context Not Found")
+			return
+		}
+	}
+
+	// from now on, a failure is reported along with the jobs already started
+	for i, contextYAML := range data.Contexts {
+		importedContext := importedContexts[i]
+
+		if importedContext == nil {
+			importedContext = &Context{
+				Name: contextYAML.Name,
+			}
+
+			if data.PreserveContextID {
+				importedContext.ID = contextYAML.ID
+			}
+
+			if importedContext, err = engine.createContext(importedContext); err != nil {
+				response.Error = NewProtocolError(ErrUnknown, err)
+				err = nil
+				return
+			}
+		}
+
+		response.Contexts = append(response.Contexts, importedContext.MarshalProtocol())
+
+		if contextYAML.Weight != 0 && contextYAML.Weight != importedContext.getWeight() {
+			if err = engine.setContextWeight(importedContext, contextYAML.Weight); err != nil {
+				response.Error = NewProtocolError(ErrUnknown, err)
+				err = nil
+				return
+			}
+		}
+
+		for _, sourceYAML := range contextYAML.Sources {
+			var job *Job
+
+			if job, err = engine.importSource(importedContext, sourceYAML, data.PreserveSourceID); err != nil {
+				response.Error = NewProtocolError(ErrUnknown, err)
+				err = nil
+				return
+			}
+
+			response.JobIds = append(response.JobIds, job.ID)
+		}
+	}
+
+	for _, sourceYAML := range data.Sources {
+		var job *Job
+
+		if job, err = engine.importSource(targetContext, sourceYAML, data.PreserveSourceID); err != nil {
+			response.Error = NewProtocolError(ErrUnknown, err)
+			err = nil
+			return
+		}
+
+		response.JobIds = append(response.JobIds, job.ID)
+	}
+
+	fmt.Printf("  importing %d context(s) from '%s' in %d job(s)\n", len(response.Contexts), request.InputPath, len(response.JobIds))
+
+	return
+}
+
 func (engine *Engine) initializeDatabase() (err error) {
 	fmt.Printf("Initializing master database\n")
 	engine.database, err = clover.Open(fmt.Sprintf("%s/__master", engine.config.PathData))
@@ -509,29 +1348,36 @@ func (engine *Engine) loadContexts() (err error) {
 			return
 		}
 
-		if err = context.initializeIndex(); err != nil {
+		var isRebuilt bool
+
+		if isRebuilt, err = context.initializeIndex(); err != nil {
 			return
 		}
 
+		engine.contextsMutex.Lock()
 		engine.contexts[contextID] = context
+		engine.contextsMutex.Unlock()
+
+		// the sources of a rebuilt index are watched once indexed again
+		if isRebuilt {
+			_, err = engine.reindexSources(context, context)
+		} else {
+			err = engine.watchContextSources(context)
+		}
+
+		if err != nil {
+			return
+		}
 	}
 
+	engine.catchUpWatchedSources()
 	return
 }
 
 func (engine *Engine) setupDefaultContext() (err error) {
 	fmt.Printf("Setting up default context\n")
 
-	hasDefaultContext := false
-	for _, context := range engine.contexts {
-		fmt.Printf("context: %+v\n", context)
-		if context.IsDefault {
-			hasDefaultContext = true
-			break
-		}
-	}
-
-	if !hasDefaultContext {
+	if _, hasDefaultContext := engine.resolveContext(""); !hasDefaultContext {
 		defaultContext, err := engine.createContext(&Context{
 			Name:      "_default",
 			IsDefault: true,
@@ -572,20 +1418,154 @@ func (engine *Engine) setupDefaultContext() (err error) {
 	return
 }
 
+func (engine *Engine) getIsStopping() bool {
+	engine.serverMutex.Lock()
+	defer engine.serverMutex.Unlock()
+
+	return engine.isStopping
+}
+
+// listen serves the RPCs until the engine stops; an engine already stopping
+// does not serve at all
 func (engine *Engine) listen() (err error) {
-	server := grpc.NewServer()
+	engine.serverMutex.Lock()
+
+	if engine.isStopping {
+		engine.serverMutex.Unlock()
+		return
+	}
 
 	if engine.config.GRPCListener, err = net.Listen(
 		"tcp",
 		fmt.Sprintf(":%d", engine.config.GRPCPort),
 	); err != nil {
+		engine.serverMutex.Unlock()
 		return
 	}
 
+	server := grpc.NewServer()
 	protocol.RegisterRispServer(server, engine)
+	engine.server = server
+
+	engine.serverMutex.Unlock()
 
 	fmt.Printf("GRPC server listening on %s\n", engine.config.GRPCListener.Addr().String())
-	return server.Serve(engine.config.GRPCListener)
+
+	// a server stopped before serving refuses to
+	if err = server.Serve(engine.config.GRPCListener); errors.Is(err, grpc.ErrServerStopped) {
+		err = nil
+	}
+
+	return
+}
+
+// waitUntil runs wait in the background and reports whether it returned
+// before the deadline
+func waitUntil(deadline time.Time, wait func()) bool {
+	done := make(chan struct{})
+
+	go func() {
+		wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(time.Until(deadline)):
+		return false
+	}
+}
+
+// resolveContext looks up the context with the given ID, falling back to the
+// default context when the ID is empty
+func (engine *Engine) resolveContext(contextID string) (*Context, bool) {
+	if contextID != "" {
+		return engine.getContext(contextID)
+	}
+
+	engine.contextsMutex.RLock()
+	defer engine.contextsMutex.RUnlock()
+
+	for _, context := range engine.contexts {
+		if context.getIsDefault() {
+			return context, true
+		}
+	}
+
+	return nil, false
+}
+
+func (engine *Engine) getContext(contextID string) (*Context, bool) {
+	engine.contextsMutex.RLock()
+	defer engine.contextsMutex.RUnlock()
+
+	context, hasContext := engine.contexts[contextID]
+	return context, hasContext
+}
+
+// resolveQueryContexts lists the contexts a query searches: every context, the
+// requested ones, or else the single context resolveContext picks
+func (engine *Engine) resolveQueryContexts(request *protocol.QueryRequest) (contexts []*Context, missingContextID string) {
+	if request.AllContexts {
+		return engine.getContexts(), ""
+	}
+
+	if len(request.ContextIds) < 1 {
+		if context, hasContext := engine.resolveContext(request.ContextId); hasContext {
+			contexts = append(contexts, context)
+		}
+
+		return
+	}
+
+	isListed := map[string]bool{}
+
+	for _, contextID := range request.ContextIds {
+		context, hasContext := engine.getContext(contextID)
+		if !hasContext {
+			return nil, contextID
+		}
+
+		if !isListed[contextID] {
+			isListed[contextID] = true
+			contexts = append(contexts, context)
+		}
+	}
+
+	return
+}
+
+// findContext looks up a context by its ID, or else by its name
+func (engine *Engine) findContext(contextIDOrName string) (*Context, bool) {
+	if context, hasContext := engine.getContext(contextIDOrName); hasContext {
+		return context, true
+	}
+
+	return engine.findContextByName(contextIDOrName)
+}
+
+func (engine *Engine) findContextByName(name string) (*Context, bool) {
+	for _, context := range engine.getContexts() {
+		if context.getName() == name {
+			return context, true
+		}
+	}
+
+	return nil, false
+}
+
+func (engine *Engine) getContexts() (contexts []*Context) {
+	engine.contextsMutex.RLock()
+	defer engine.contextsMutex.RUnlock()
+
+	contexts = make([]*Context, 0, len(engine.contexts))
+
+	for _, context := range engine.contexts {
+		contexts = append(contexts, context)
+	}
+
+	return
 }
 
 func (engine *Engine) createContext(context *Context) (*Context, error) {
@@ -598,14 +1578,216 @@ func (engine *Engine) createContext(context *Context) (*Context, error) {
 	document := clover.NewDocument()
 	document.SetAll(context.MarshalMap())
 
+	if context.ID != "" {
+		document.Set(FieldObjectID, context.ID)
+	}
+
 	if context.ID, err = engine.database.InsertOne(ColContexts, document); err != nil {
 		return context, err
 	}
 
-	if err = context.initializeIndex(); err != nil {
+	if _, err = context.initializeIndex(); err != nil {
 		return context, err
 	}
 
+	engine.contextsMutex.Lock()
 	engine.contexts[context.ID] = context
+	engine.contextsMutex.Unlock()
+
+	engine.events.PublishContext(protocol.EventType_CONTEXT_CREATED, context)
 	return context, nil
 }
+
+// validateDataYAML checks a dump as a whole, so that an invalid dump imports
+// nothing rather than part of it
+func validateDataYAML(data *dump.DataYAML) (err error) {
+	names := map[string]bool{}
+
+	for _, contextYAML := range data.Contexts {
+		if len(contextYAML.Name) < 1 {
+			return fmt.Errorf("context without a name")
+		}
+
+		if names[contextYAML.Name] {
+			return fmt.Errorf("context '%s' listed twice", contextYAML.Name)
+		}
+
+		names[contextYAML.Name] = true
+
+		if contextYAML.Weight != 0 {
+			if err = validateWeight(contextYAML.Weight); err != nil {
+				return fmt.Errorf("context '%s': %w", contextYAML.Name, err)
+			}
+		}
+
+		if err = validateSourcesYAML(contextYAML.Sources); err != nil {
+			return fmt.Errorf("context '%s': %w", contextYAML.Name, err)
+		}
+	}
+
+	return validateSourcesYAML(data.Sources)
+}
+
+func validateSourcesYAML(sources []*dump.SourceYAML) (err error) {
+	for _, sourceYAML := range sources {
+		if _, _, err = canonicalizeSourceURI(sourceYAML.URI); err != nil {
+			return
+		}
+
+		if sourceYAML.Weight != 0 {
+			if err = validateWeight(sourceYAML.Weight); err != nil {
+				return fmt.Errorf("source '%s': %w", sourceYAML.URI, err)
+			}
+		}
+	}
+
+	return
+}
+
+// importSource queues the indexing of a source of a dump, along with its web
+// pages when listed
+func (engine *Engine) importSource(context *Context, sourceYAML *dump.SourceYAML, preserveSourceID bool) (job *Job, err error) {
+	var sourceID string
+
+	if preserveSourceID && sourceYAML.ID != "" {
+		var document *clover.Document
+
+		if document, err = engine.database.Query(ColSources).FindById(sourceYAML.ID); err != nil {
+			return
+		}
+
+		if document == nil {
+			sourceID = sourceYAML.ID
+		}
+	}
+
+	uris := []string{sourceYAML.URI}
+
+	if _, adapterType, _ := canonicalizeSourceURI(sourceYAML.URI); adapterType == AdapterTypeWeb && len(sourceYAML.Resources) > 0 {
+		uris = make([]string, 0)
+
+		for _, resourceURI := range sourceYAML.Resources {
+			uris = append(uris, fmt.Sprintf("%s%s", strings.TrimSuffix(sourceYAML.URI, "/"), resourceURI))
+		}
+	}
+
+	job = engine.startSourceJob(context, sourceYAML.URI, uris, sourceID, func(source *Source) error {
+		if sourceYAML.Weight != 0 && sourceYAML.Weight != source.weight() {
+			return context.UpdateSourceWeight(source, sourceYAML.Weight)
+		}
+
+		return nil
+	})
+
+	return
+}
+
+func (engine *Engine) setContextWeight(context *Context, weight float64) (err error) {
+	if err = engine.database.Query(ColContexts).UpdateById(context.ID, map[string]interface{}{
+		"weight": weight,
+	}); err != nil {
+		return
+	}
+
+	context.setWeight(weight)
+	return
+}
+
+// setDefaultContext holds the contexts lock throughout, so that concurrent
+// calls cannot leave several contexts flagged as default
+func (engine *Engine) setDefaultContext(context *Context) (err error) {
+	var previousDefaults []*Context
+
+	engine.contextsMutex.Lock()
+
+	if _, hasContext := engine.contexts[context.ID]; !hasContext {
+		engine.contextsMutex.Unlock()
+		return fmt.Errorf("context '%s' not found", context.ID)
+	}
+
+	if err = engine.database.Query(ColContexts).Where(
+		clover.Field("isDefault").IsTrue(),
+	).Update(map[string]interface{}{
+		"isDefault": false,
+	}); err != nil {
+		engine.contextsMutex.Unlock()
+		return
+	}
+
+	if err = engine.database.Query(ColContexts).UpdateById(context.ID, map[string]interface{}{
+		"isDefault": true,
+	}); err != nil {
+		engine.contextsMutex.Unlock()
+		return
+	}
+
+	for _, otherContext := range engine.contexts {
+		if otherContext != context && otherContext.getIsDefault() {
+			otherContext.setIsDefault(false)
+			previousDefaults = append(previousDefaults, otherContext)
+		}
+	}
+
+	context.setIsDefault(true)
+
+	engine.contextsMutex.Unlock()
+
+	for _, previousDefault := range previousDefaults {
+		engine.events.PublishContext(protocol.EventType_CONTEXT_UPDATED, previousDefault)
+	}
+
+	return
+}
+
+func (engine *Engine) deleteContext(context *Context) (err error) {
+	engine.contextsMutex.Lock()
+
+	if context.getIsDefault() {
+		engine.contextsMutex.Unlock()
+		return errDefaultContext
+	}
+
+	// of concurrent deletes of a context, only the first one purges it
+	if _, hasContext := engine.contexts[context.ID]; !hasContext {
+		engine.contextsMutex.Unlock()
+		return errContextNotFound
+	}
+
+	delete(engine.contexts, context.ID)
+
+	engine.contextsMutex.Unlock()
+
+	engine.unwatchContext(context.ID)
+	engine.cancelJobs(engine.getJobs(context.ID))
+
+	if err = context.closeIndex(); err != nil {
+		return
+	}
+
+	if err = os.RemoveAll(context.GetIndexPath()); err != nil {
+		return
+	}
+
+	if _, err = deleteWhere(engine.database, ColResources, clover.Field("contextId").Eq(context.ID)); err != nil {
+		return
+	}
+
+	if _, err = deleteWhere(engine.database, ColSources, clover.Field("contextId").Eq(context.ID)); err != nil {
+		return
+	}
+
+	if _, err = deleteWhere(engine.database, ColSavedQueries, clover.Field("contextId").Eq(context.ID)); err != nil {
+		return
+	}
+
+	if _, err = context.ClearSearchHistory(); err != nil {
+		return
+	}
+
+	if err = engine.database.Query(ColContexts).DeleteById(context.ID); err != nil {
+		return
+	}
+
+	engine.events.PublishContext(protocol.EventType_CONTEXT_DELETED, context)
+	return
+}
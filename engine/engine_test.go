@@ -0,0 +1,332 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/necessitates/clover"
+
+	"risp/config"
+	"risp/dump"
+	"risp/protocol"
+)
+
+func newTestEngine(t *testing.T) (engine *Engine, defaultContext *Context) {
+	t.Helper()
+
+	engine = NewEngine(&config.Config{PathData: t.TempDir()})
+
+	if err := engine.initializeDatabase(); err != nil {
+		t.Fatalf("initializing the database: %+v", err)
+	}
+
+	defaultContext, err := engine.createContext(&Context{
+		Name:      "_default",
+		IsDefault: true,
+	})
+	if err != nil {
+		t.Fatalf("creating the default context: %+v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := engine.Stop(); err != nil {
+			t.Errorf("stopping the engine: %+v", err)
+		}
+	})
+
+	return
+}
+
+func newTestSourceDir(t *testing.T, files int) string {
+	t.Helper()
+
+	contents := map[string]string{}
+
+	for i := 0; i < files; i++ {
+		contents[fmt.Sprintf("file-%02d.txt", i)] = fmt.Sprintf("resource number %d of the stress test", i)
+	}
+
+	return newTestSourceDirOf(t, contents)
+}
+
+// newTestSourceDirOf writes the files of a source, by their path relative to
+// it, and returns its URI
+func newTestSourceDirOf(t *testing.T, contents map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	for path, content := range contents {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("creating a source directory: %+v", err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("writing a source file: %+v", err)
+		}
+	}
+
+	return "file://" + dir
+}
+
+// indexTestSource indexes a source into a context, and waits for it
+func indexTestSource(t *testing.T, engine *Engine, context *Context, uri string) (job *Job, source *Source) {
+	t.Helper()
+
+	job = engine.startJob(context, uri)
+	job.wait()
+
+	if len(job.Errors) > 0 {
+		t.Fatalf("indexing %s: %v", uri, job.Errors)
+	}
+
+	source, err := context.GetSource(job.SourceID)
+	if err != nil {
+		t.Fatalf("getting the source of %s: %+v", uri, err)
+	}
+
+	return
+}
+
+// TestConcurrentRPCs indexes, queries, clones and deletes contexts all at
+// once; run it with -race. Whatever the interleaving, no RPC may fail, no URN
+// may be stored twice, and no deleted context may leave anything behind
+func TestConcurrentRPCs(t *testing.T) {
+	const (
+		workers    = 4
+		iterations = 8
+	)
+
+	engine, defaultContext := newTestEngine(t)
+	sourceURIs := []string{newTestSourceDir(t, 10), newTestSourceDir(t, 10)}
+
+	var (
+		contextIDs      = []string{defaultContext.ID}
+		contextIDsMutex sync.Mutex
+		group           sync.WaitGroup
+	)
+
+	for i := 0; i < workers; i++ {
+		response, err := engine.CreateContext(nil, &protocol.CreateContextRequest{
+			Name: fmt.Sprintf("stress %d", i),
+		})
+		if err != nil || response.Error.Code != ErrAllGood {
+			t.Fatalf("creating a context: %+v %+v", err, response.Error)
+		}
+
+		contextIDs = append(contextIDs, response.Context.Id)
+	}
+
+	randomContextID := func(random *rand.Rand) string {
+		contextIDsMutex.Lock()
+		defer contextIDsMutex.Unlock()
+
+		return contextIDs[random.Intn(len(contextIDs))]
+	}
+
+	run := func(name string, call func(random *rand.Rand) error) {
+		for i := 0; i < workers; i++ {
+			group.Add(1)
+
+			go func(seed int64) {
+				defer group.Done()
+
+				random := rand.New(rand.NewSource(seed))
+
+				for j := 0; j < iterations; j++ {
+					if err := call(random); err != nil {
+						t.Errorf("%s: %+v", name, err)
+						return
+					}
+				}
+			}(int64(i))
+		}
+	}
+
+	run("IndexURI", func(random *rand.Rand) error {
+		_, err := engine.IndexURI(nil, &protocol.IndexURIRequest{
+			ContextId: randomContextID(random),
+			Uri:       sourceURIs[random.Intn(len(sourceURIs))],
+		})
+		return err
+	})
+
+	run("Query", func(random *rand.Rand) error {
+		_, err := engine.Query(nil, &protocol.QueryRequest{
+			ContextId: randomContextID(random),
+			Value:     "resource",
+		})
+		return err
+	})
+
+	run("CloneContext", func(random *rand.Rand) error {
+		response, err := engine.CloneContext(nil, &protocol.CloneContextRequest{
+			ContextId: randomContextID(random),
+		})
+		if err != nil {
+			return err
+		}
+
+		if response.Context != nil {
+			contextIDsMutex.Lock()
+			contextIDs = append(contextIDs, response.Context.Id)
+			contextIDsMutex.Unlock()
+		}
+
+		return nil
+	})
+
+	run("DeleteContext", func(random *rand.Rand) error {
+		_, err := engine.DeleteContext(nil, &protocol.DeleteContextRequest{
+			ContextId: randomContextID(random),
+		})
+		return err
+	})
+
+	group.Wait()
+	engine.jobsGroup.Wait()
+
+	for _, collection := range []string{ColSources, ColResources} {
+		documents, err := engine.database.Query(collection).FindAll()
+		if err != nil {
+			t.Fatalf("listing %s: %+v", collection, err)
+		}
+
+		documentsByURN := map[string][]*clover.Document{}
+
+		for _, document := range documents {
+			assertContextExists(t, engine, collection, document)
+
+			urn, _ := document.Get("urn").(string)
+			documentsByURN[urn] = append(documentsByURN[urn], document)
+		}
+
+		for urn, documents := range documentsByURN {
+			if len(documents) > 1 {
+				t.Errorf("%s has %d documents of URN %s", collection, len(documents), urn)
+			}
+		}
+	}
+
+	for _, context := range engine.getContexts() {
+		if _, err := context.index.DocCount(); err != nil {
+			t.Errorf("index of context %s: %+v", context.ID, err)
+		}
+	}
+}
+
+func assertContextExists(t *testing.T, engine *Engine, collection string, document *clover.Document) {
+	t.Helper()
+
+	contextID, _ := document.Get("contextId").(string)
+
+	if _, hasContext := engine.getContext(contextID); !hasContext {
+		t.Errorf("%s %s left behind by deleted context %s", collection, document.ObjectId(), contextID)
+	}
+}
+
+// TestStopRefusesJobs starts a job once the engine stopped, as an RPC still
+// draining would; the job is cancelled rather than run
+func TestStopRefusesJobs(t *testing.T) {
+	engine, defaultContext := newTestEngine(t)
+	sourceURI := newTestSourceDir(t, 1)
+
+	if err := engine.Stop(); err != nil {
+		t.Fatalf("stopping the engine: %+v", err)
+	}
+
+	job := engine.startJob(defaultContext, sourceURI)
+	job.wait()
+
+	if job.Status != JobCancelled {
+		t.Errorf("job is %s, want %s", job.Status, JobCancelled)
+	}
+
+	engine.jobsGroup.Wait()
+}
+
+// TestImportContexts imports a dump twice, its contexts being reused by name
+// the second time and the local default context staying the default, then an
+// invalid dump which imports nothing
+func TestImportContexts(t *testing.T) {
+	engine, defaultContext := newTestEngine(t)
+	sourceURI := newTestSourceDir(t, 2)
+
+	importDump := func(data *dump.DataYAML) *protocol.ImportContextsResponse {
+		t.Helper()
+
+		dumpPath := filepath.Join(t.TempDir(), "dump.yaml")
+
+		dumpData, err := dump.EncodeDataYAML(data)
+		if err != nil {
+			t.Fatalf("encoding the dump: %+v", err)
+		}
+
+		if err = os.WriteFile(dumpPath, dumpData, 0644); err != nil {
+			t.Fatalf("writing the dump: %+v", err)
+		}
+
+		response, err := engine.ImportContexts(nil, &protocol.ImportContextsRequest{
+			InputPath: dumpPath,
+		})
+		if err != nil {
+			t.Fatalf("importing the dump: %+v", err)
+		}
+
+		for _, jobID := range response.JobIds {
+			if job, hasJob := engine.getJob(jobID); hasJob {
+				job.wait()
+			}
+		}
+
+		return response
+	}
+
+	data := &dump.DataYAML{
+		Contexts: []*dump.ContextYAML{
+			{Name: "_default"},
+			{Name: "imported", IsDefault: true, Sources: []*dump.SourceYAML{{URI: sourceURI}}},
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		response := importDump(data)
+
+		if response.Error.Code != ErrAllGood || len(response.Contexts) != 2 || len(response.JobIds) != 1 {
+			t.Fatalf("import %d: %+v", i, response)
+		}
+
+		if response.Contexts[0].Id != defaultContext.ID {
+			t.Errorf("import %d: _default imported into %s, want %s", i, response.Contexts[0].Id, defaultContext.ID)
+		}
+	}
+
+	if contexts := engine.getContexts(); len(contexts) != 2 {
+		t.Errorf("%d contexts after importing twice, want 2", len(contexts))
+	}
+
+	if !defaultContext.getIsDefault() {
+		t.Errorf("the dump took the default away from the local default context")
+	}
+
+	response := importDump(&dump.DataYAML{
+		Contexts: []*dump.ContextYAML{
+			{Name: "valid", Sources: []*dump.SourceYAML{{URI: sourceURI}}},
+			{Name: "invalid", Sources: []*dump.SourceYAML{{URI: "ftp://example.com"}}},
+		},
+	})
+
+	if response.Error.Code != ErrInvalidDump || len(response.JobIds) > 0 {
+		t.Errorf("invalid dump: %+v", response)
+	}
+
+	if _, hasContext := engine.findContextByName("valid"); hasContext {
+		t.Errorf("invalid dump imported in part")
+	}
+}
@@ -13,6 +13,10 @@ const (
 	ErrInvalidContext
 	ErrInvalidSource
 	ErrInvalidResource
+	ErrInvalidDump
+	ErrInvalidJob
+	ErrInvalidSavedQuery
+	ErrInvalidSearch
 )
 
 func NewProtocolError(opts ...interface{}) *protocol.Error {
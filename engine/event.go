@@ -0,0 +1,201 @@
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"risp/protocol"
+)
+
+const subscriptionBufferSize = 256
+
+type Subscription struct {
+	Events     chan *protocol.Event
+	contextIDs map[string]bool
+	types      map[protocol.EventType]bool
+}
+
+func (subscription *Subscription) accepts(event *protocol.Event) bool {
+	if len(subscription.contextIDs) > 0 && !subscription.contextIDs[event.ContextId] {
+		return false
+	}
+
+	if len(subscription.types) > 0 && !subscription.types[event.Type] {
+		return false
+	}
+
+	return true
+}
+
+/**
+ * EventBus : Fans engine activity out to subscribers; a nil *EventBus drops
+ * every event, and slow subscribers miss events rather than block the engine
+ */
+
+type EventBus struct {
+	subscriptions map[*Subscription]bool
+	isClosed      bool
+	mutex         sync.RWMutex
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscriptions: map[*Subscription]bool{},
+	}
+}
+
+func (eventBus *EventBus) Subscribe(contextIDs []string, types []protocol.EventType) *Subscription {
+	subscription := &Subscription{
+		Events:     make(chan *protocol.Event, subscriptionBufferSize),
+		contextIDs: map[string]bool{},
+		types:      map[protocol.EventType]bool{},
+	}
+
+	for _, contextID := range contextIDs {
+		subscription.contextIDs[contextID] = true
+	}
+
+	for _, eventType := range types {
+		subscription.types[eventType] = true
+	}
+
+	eventBus.mutex.Lock()
+	defer eventBus.mutex.Unlock()
+
+	if eventBus.isClosed {
+		close(subscription.Events)
+		return subscription
+	}
+
+	eventBus.subscriptions[subscription] = true
+	return subscription
+}
+
+func (eventBus *EventBus) Unsubscribe(subscription *Subscription) {
+	eventBus.mutex.Lock()
+	defer eventBus.mutex.Unlock()
+
+	if eventBus.subscriptions[subscription] {
+		delete(eventBus.subscriptions, subscription)
+		close(subscription.Events)
+	}
+}
+
+// Close ends every subscription, letting streaming RPCs return
+func (eventBus *EventBus) Close() {
+	eventBus.mutex.Lock()
+	defer eventBus.mutex.Unlock()
+
+	eventBus.isClosed = true
+
+	for subscription := range eventBus.subscriptions {
+		delete(eventBus.subscriptions, subscription)
+		close(subscription.Events)
+	}
+}
+
+func (eventBus *EventBus) Publish(event *protocol.Event) {
+	if eventBus == nil || event == nil {
+		return
+	}
+
+	event.Time = time.Now().UnixMilli()
+
+	eventBus.mutex.RLock()
+	defer eventBus.mutex.RUnlock()
+
+	for subscription := range eventBus.subscriptions {
+		if !subscription.accepts(event) {
+			continue
+		}
+
+		select {
+		case subscription.Events <- event:
+		default:
+		}
+	}
+}
+
+func (eventBus *EventBus) PublishContext(eventType protocol.EventType, context *Context) {
+	if eventBus == nil {
+		return
+	}
+
+	eventBus.Publish(&protocol.Event{
+		Type:      eventType,
+		ContextId: context.ID,
+		Context:   context.MarshalProtocol(),
+	})
+}
+
+func (eventBus *EventBus) PublishSource(eventType protocol.EventType, source *Source) {
+	if eventBus == nil {
+		return
+	}
+
+	eventBus.Publish(&protocol.Event{
+		Type:      eventType,
+		ContextId: source.ContextID,
+		Source:    source.MarshalProtocol(),
+	})
+}
+
+func (eventBus *EventBus) PublishResourceIndexed(resource Resource) {
+	if eventBus == nil {
+		return
+	}
+
+	resourceProto := resource.MarshalProtocol()
+
+	eventBus.Publish(&protocol.Event{
+		Type:       protocol.EventType_RESOURCE_INDEXED,
+		ContextId:  resourceProto.ContextId,
+		Resource:   resourceProto,
+		ResourceId: resourceProto.Id,
+	})
+}
+
+func (eventBus *EventBus) PublishResourceRemoved(contextID string, resourceID string) {
+	eventBus.Publish(&protocol.Event{
+		Type:       protocol.EventType_RESOURCE_REMOVED,
+		ContextId:  contextID,
+		ResourceId: resourceID,
+	})
+}
+
+func (eventBus *EventBus) PublishJob(job *Job) {
+	if eventBus == nil || job == nil {
+		return
+	}
+
+	eventBus.Publish(&protocol.Event{
+		Type:      protocol.EventType_JOB_PROGRESS,
+		ContextId: job.ContextID,
+		Job:       job.MarshalProtocol(),
+	})
+}
+
+func (eventBus *EventBus) PublishError(contextID string, err error) {
+	if eventBus == nil || err == nil {
+		return
+	}
+
+	eventBus.Publish(&protocol.Event{
+		Type:      protocol.EventType_ERROR,
+		ContextId: contextID,
+		Error:     NewProtocolError(ErrUnknown, err),
+	})
+}
+
+func (eventBus *EventBus) PublishSavedQueryMatched(savedQuery *SavedQuery, resourceIDs []string) {
+	if eventBus == nil || savedQuery == nil {
+		return
+	}
+
+	eventBus.Publish(&protocol.Event{
+		Type:        protocol.EventType_SAVED_QUERY_MATCHED,
+		ContextId:   savedQuery.ContextID,
+		SavedQuery:  savedQuery.MarshalProtocol(),
+		ResourceIds: resourceIDs,
+	})
+}
@@ -0,0 +1,16 @@
+package engine
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileChangeTime reads the time the file's inode last changed
+func fileChangeTime(fileInfo os.FileInfo) time.Time {
+	if stat, isStat := fileInfo.Sys().(*syscall.Stat_t); isStat {
+		return time.Unix(int64(stat.Ctim.Sec), int64(stat.Ctim.Nsec))
+	}
+
+	return fileInfo.ModTime()
+}
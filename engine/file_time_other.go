@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package engine
+
+import (
+	"os"
+	"time"
+)
+
+// fileChangeTime falls back to the modification time where the platform
+// keeps no change time
+func fileChangeTime(fileInfo os.FileInfo) time.Time {
+	return fileInfo.ModTime()
+}
@@ -2,6 +2,8 @@ package engine
 
 import (
 	"bytes"
+	"strings"
+	"time"
 
 	"golang.org/x/net/html"
 )
@@ -20,6 +22,78 @@ func findHTMLNode(node *html.Node, check func(*html.Node) bool) (*html.Node, boo
 	return nil, false
 }
 
+func findHTMLTitle(document *html.Node) string {
+	titleNode, hasTitleNode := findHTMLNode(document, func(node *html.Node) bool {
+		return node.Type == html.ElementNode && node.Data == "title"
+	})
+
+	if !hasTitleNode || titleNode.FirstChild == nil {
+		return ""
+	}
+
+	return strings.TrimSpace(titleNode.FirstChild.Data)
+}
+
+// findHTMLLanguage reads the lang attribute of the root element, so it must
+// run before sanitizeHTMLDocument drops the attributes
+func findHTMLLanguage(document *html.Node) string {
+	htmlNode, hasHTMLNode := findHTMLNode(document, func(node *html.Node) bool {
+		return node.Type == html.ElementNode && node.Data == "html"
+	})
+
+	if !hasHTMLNode {
+		return ""
+	}
+
+	for _, attribute := range htmlNode.Attr {
+		if attribute.Key == "lang" {
+			return strings.ToLower(strings.TrimSpace(attribute.Val))
+		}
+	}
+
+	return ""
+}
+
+// findHTMLMeta reads the content of the meta tag of the given property, or
+// name, so it must run before sanitizeHTMLDocument drops the head
+func findHTMLMeta(document *html.Node, property string) string {
+	metaNode, hasMetaNode := findHTMLNode(document, func(node *html.Node) bool {
+		if node.Type != html.ElementNode || node.Data != "meta" {
+			return false
+		}
+
+		for _, attribute := range node.Attr {
+			if (attribute.Key == "property" || attribute.Key == "name") && attribute.Val == property {
+				return true
+			}
+		}
+
+		return false
+	})
+
+	if !hasMetaNode {
+		return ""
+	}
+
+	for _, attribute := range metaNode.Attr {
+		if attribute.Key == "content" {
+			return strings.TrimSpace(attribute.Val)
+		}
+	}
+
+	return ""
+}
+
+// parseHTMLTime reads the ISO 8601 times of meta tags, which may leave out
+// the time of day
+func parseHTMLTime(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+
+	return time.Parse("2006-01-02", value)
+}
+
 func removeAllTagsByName(name string, node *html.Node) {
 	if node.Type == html.ElementNode && node.Data == name {
 		node.Parent.RemoveChild(node)
@@ -0,0 +1,440 @@
+package engine
+
+import (
+	_context "context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/necessitates/clover"
+
+	"risp/protocol"
+)
+
+type JobStatus string
+
+const (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobCompleted JobStatus = "completed"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+const jobsRetention = time.Hour
+
+// resourceChange tells how a processed resource differs from when it was
+// last indexed
+type resourceChange int
+
+const (
+	resourceAdded resourceChange = iota
+	resourceUpdated
+	resourceUnchanged
+)
+
+/**
+ * Job : A background indexing of a single URI; adapters report progress and
+ * observe cancellation through it, and a nil *Job is a valid no-op job
+ */
+
+type Job struct {
+	ID                 string
+	ContextID          string
+	SourceID           string
+	URI                string
+	Status             JobStatus
+	ResourcesProcessed int64
+	ResourcesAdded     int64
+	ResourcesUpdated   int64
+	ResourcesUnchanged int64
+	ResourcesRemoved   int64
+	Errors             []string
+	StartedAt          time.Time
+	FinishedAt         time.Time
+	context            _context.Context
+	cancel             _context.CancelFunc
+	done               chan struct{} // closed once the job and its follow-ups are over
+	events             *EventBus
+	mutex              sync.RWMutex
+}
+
+func NewJob(contextID string, uri string, events *EventBus) *Job {
+	job := &Job{
+		ID:        clover.NewObjectId(),
+		ContextID: contextID,
+		URI:       uri,
+		Status:    JobPending,
+		Errors:    make([]string, 0),
+		done:      make(chan struct{}),
+		events:    events,
+	}
+
+	job.context, job.cancel = _context.WithCancel(_context.Background())
+
+	return job
+}
+
+func (job *Job) Context() _context.Context {
+	if job == nil {
+		return _context.Background()
+	}
+
+	return job.context
+}
+
+func (job *Job) Cancel() {
+	if job == nil {
+		return
+	}
+
+	job.cancel()
+}
+
+// wait blocks until the job is over, along with what follows its run
+func (job *Job) wait() {
+	<-job.done
+}
+
+// hasChanges tells whether the job added, updated or removed any resource
+func (job *Job) hasChanges() bool {
+	job.mutex.RLock()
+	defer job.mutex.RUnlock()
+
+	return job.ResourcesAdded+job.ResourcesUpdated+job.ResourcesRemoved > 0
+}
+
+func (job *Job) IsFinished() bool {
+	job.mutex.RLock()
+	defer job.mutex.RUnlock()
+
+	return job.Status == JobCompleted || job.Status == JobFailed || job.Status == JobCancelled
+}
+
+func (job *Job) Run(index func(job *Job) (*Source, error)) {
+	var (
+		source *Source
+		err    error
+	)
+
+	job.mutex.Lock()
+	job.Status = JobRunning
+	job.StartedAt = time.Now()
+	job.mutex.Unlock()
+
+	job.events.PublishJob(job)
+
+	source, err = index(job)
+
+	defer job.events.PublishJob(job)
+
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+
+	job.FinishedAt = time.Now()
+
+	if source != nil {
+		job.SourceID = source.ID
+	}
+
+	switch true {
+	case errors.Is(err, _context.Canceled) || job.context.Err() != nil:
+		job.Status = JobCancelled
+	case err != nil:
+		job.Status = JobFailed
+		job.Errors = append(job.Errors, err.Error())
+		job.events.PublishError(job.ContextID, err)
+	default:
+		job.Status = JobCompleted
+	}
+
+	job.cancel()
+}
+
+func (job *Job) MarshalProtocol() *protocol.Job {
+	job.mutex.RLock()
+	defer job.mutex.RUnlock()
+
+	jobProto := &protocol.Job{
+		Id:                 job.ID,
+		ContextId:          job.ContextID,
+		SourceId:           job.SourceID,
+		Uri:                job.URI,
+		ResourcesProcessed: job.ResourcesProcessed,
+		ResourcesAdded:     job.ResourcesAdded,
+		ResourcesUpdated:   job.ResourcesUpdated,
+		ResourcesUnchanged: job.ResourcesUnchanged,
+		ResourcesRemoved:   job.ResourcesRemoved,
+		Errors:             append([]string{}, job.Errors...),
+	}
+
+	switch job.Status {
+	case JobPending:
+		jobProto.Status = protocol.JobStatus_PENDING
+	case JobRunning:
+		jobProto.Status = protocol.JobStatus_RUNNING
+	case JobCompleted:
+		jobProto.Status = protocol.JobStatus_COMPLETED
+	case JobFailed:
+		jobProto.Status = protocol.JobStatus_FAILED
+	case JobCancelled:
+		jobProto.Status = protocol.JobStatus_CANCELLED
+	}
+
+	if !job.StartedAt.IsZero() {
+		jobProto.StartedAt = job.StartedAt.UnixMilli()
+
+		if job.FinishedAt.IsZero() {
+			jobProto.Elapsed = time.Since(job.StartedAt).Milliseconds()
+		} else {
+			jobProto.FinishedAt = job.FinishedAt.UnixMilli()
+			jobProto.Elapsed = job.FinishedAt.Sub(job.StartedAt).Milliseconds()
+		}
+	}
+
+	return jobProto
+}
+
+func (job *Job) isExpired() bool {
+	job.mutex.RLock()
+	defer job.mutex.RUnlock()
+
+	return !job.FinishedAt.IsZero() && time.Since(job.FinishedAt) > jobsRetention
+}
+
+// checkpoint returns the cancellation error once the job has been cancelled
+func (job *Job) checkpoint() error {
+	if job == nil {
+		return nil
+	}
+
+	return job.context.Err()
+}
+
+func (job *Job) resourceProcessed(change resourceChange) {
+	if job == nil {
+		return
+	}
+
+	job.mutex.Lock()
+	job.ResourcesProcessed++
+
+	switch change {
+	case resourceAdded:
+		job.ResourcesAdded++
+	case resourceUpdated:
+		job.ResourcesUpdated++
+	case resourceUnchanged:
+		job.ResourcesUnchanged++
+	}
+
+	job.mutex.Unlock()
+
+	job.events.PublishJob(job)
+}
+
+func (job *Job) resourcesRemoved(count int) {
+	if job == nil || count < 1 {
+		return
+	}
+
+	job.mutex.Lock()
+	job.ResourcesRemoved += int64(count)
+	job.mutex.Unlock()
+
+	job.events.PublishJob(job)
+}
+
+// tolerate records a non-fatal crawl error and reports whether the crawl can
+// go on; without a job to report to, every error stays fatal
+func (job *Job) tolerate(err error) bool {
+	if job == nil || err == nil || job.context.Err() != nil {
+		return false
+	}
+
+	job.mutex.Lock()
+	job.Errors = append(job.Errors, err.Error())
+	job.mutex.Unlock()
+
+	job.events.PublishError(job.ContextID, err)
+	return true
+}
+
+func (engine *Engine) startJob(context *Context, uri string) (job *Job) {
+	return engine.startSourceJob(context, uri, []string{uri}, "", nil)
+}
+
+// startSourceJob indexes, one after the other in a single job, the URIs of a
+// source; sourceID is the ID given to the source if it is new, and indexed,
+// when set, follows the indexing of the last URI
+func (engine *Engine) startSourceJob(context *Context, uri string, uris []string, sourceID string, indexed func(source *Source) error) (job *Job) {
+	return engine.runJob(context, uri, func(job *Job) (source *Source, err error) {
+		for _, uri := range uris {
+			if err = job.checkpoint(); err != nil {
+				return
+			}
+
+			var uriSource *Source
+
+			if uriSource, err = context.sourceURI(uri, sourceID, job); err != nil {
+				// a page of a web source failing leaves its other pages to index
+				if len(uris) < 2 || !job.tolerate(err) {
+					return uriSource, err
+				}
+
+				err = nil
+			}
+
+			if uriSource != nil && uriSource.ID != "" {
+				source, sourceID = uriSource, uriSource.ID
+			}
+		}
+
+		if indexed != nil && source != nil {
+			err = indexed(source)
+		}
+
+		return
+	})
+}
+
+// reindexSources indexes the sources of a context again into another one, or
+// into itself, in the background, a job each
+func (engine *Engine) reindexSources(context *Context, targetContext *Context) (jobs []*Job, err error) {
+	jobs = make([]*Job, 0)
+
+	err = context.eachSource(nil, func(source *Source) error {
+		sourceURIs, err := context.GetSourceURIsOf(source)
+		if err != nil {
+			return err
+		}
+
+		jobs = append(jobs, engine.startSourceJob(targetContext, source.CanonicalURI, sourceURIs, "", nil))
+		return nil
+	})
+
+	return
+}
+
+// runJob runs an indexing in the background; once indexed, the saved queries
+// of the context are run again and the indexed source is watched
+func (engine *Engine) runJob(context *Context, uri string, index func(job *Job) (*Source, error)) (job *Job) {
+	job = NewJob(context.ID, uri, engine.events)
+
+	engine.jobsMutex.Lock()
+	for jobID, otherJob := range engine.jobs {
+		if otherJob.isExpired() {
+			delete(engine.jobs, jobID)
+		}
+	}
+
+	// a context being deleted has listed the jobs to wait for already, so a
+	// job of it added later is cancelled rather than run
+	_, hasContext := engine.getContext(context.ID)
+
+	engine.jobs[job.ID] = job
+
+	// a stopping engine may be waiting for its jobs already, so a job added
+	// later is cancelled at once, and never waited for
+	if engine.getIsStopping() {
+		engine.jobsMutex.Unlock()
+
+		job.Run(func(job *Job) (*Source, error) {
+			return nil, _context.Canceled
+		})
+
+		close(job.done)
+		return
+	}
+
+	engine.jobsGroup.Add(1)
+	engine.jobsMutex.Unlock()
+
+	go func() {
+		defer engine.jobsGroup.Done()
+		defer close(job.done)
+
+		var (
+			source   *Source
+			indexErr error
+		)
+
+		job.Run(func(job *Job) (*Source, error) {
+			if !hasContext {
+				return nil, _context.Canceled
+			}
+
+			source, indexErr = index(job)
+			return source, indexErr
+		})
+
+		// a deleted context has no saved queries left to run nor sources to watch
+		if _, hasContext := engine.getContext(context.ID); hasContext {
+			// a job leaving the resources as they were changes no match
+			if job.hasChanges() {
+				engine.evaluateSavedQueries(context)
+			}
+
+			if source != nil && indexErr == nil {
+				if err := engine.watchSource(context, source); err != nil {
+					engine.events.PublishError(context.ID, err)
+				}
+			}
+		}
+	}()
+
+	return
+}
+
+// cancelJobs cancels the jobs and waits for them to be over, so that none
+// writes to what the caller is about to delete
+func (engine *Engine) cancelJobs(jobs []*Job) {
+	for _, job := range jobs {
+		job.Cancel()
+	}
+
+	for _, job := range jobs {
+		job.wait()
+	}
+}
+
+// getSourceJobs lists the jobs of a source, telling the running ones, which
+// have yet to know their source, by their URI
+func (engine *Engine) getSourceJobs(source *Source) (jobs []*Job) {
+	jobs = make([]*Job, 0)
+
+	for _, job := range engine.getJobs(source.ContextID) {
+		job.mutex.RLock()
+		sourceID := job.SourceID
+		job.mutex.RUnlock()
+
+		if canonicalURI, _, err := canonicalizeSourceURI(job.URI); sourceID == source.ID || (err == nil && canonicalURI == source.CanonicalURI) {
+			jobs = append(jobs, job)
+		}
+	}
+
+	return
+}
+
+func (engine *Engine) getJob(jobID string) (job *Job, hasJob bool) {
+	engine.jobsMutex.RLock()
+	defer engine.jobsMutex.RUnlock()
+
+	job, hasJob = engine.jobs[jobID]
+	return
+}
+
+func (engine *Engine) getJobs(contextID string) (jobs []*Job) {
+	engine.jobsMutex.RLock()
+	defer engine.jobsMutex.RUnlock()
+
+	jobs = make([]*Job, 0)
+
+	for _, job := range engine.jobs {
+		if contextID == "" || job.ContextID == contextID {
+			jobs = append(jobs, job)
+		}
+	}
+
+	return
+}
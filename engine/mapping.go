@@ -10,6 +10,14 @@ import (
 	"github.com/blevesearch/bleve/v2/mapping"
 )
 
+// indexMappingVersion is to be raised with every change of BuildIndexMapping,
+// so that the indexes built by an older mapping are rebuilt on opening; 2
+// added the unified resource fields and the dates of resources
+const indexMappingVersion = 2
+
+// indexMappingVersionKey stores the mapping version within the index itself
+var indexMappingVersionKey = []byte("mappingVersion")
+
 func excludeFieldMapping() *mapping.FieldMapping {
 	excludeFieldMapping := bleve.NewKeywordFieldMapping()
 	excludeFieldMapping.Store = true
@@ -19,6 +27,11 @@ func excludeFieldMapping() *mapping.FieldMapping {
 	return excludeFieldMapping
 }
 
+func dateTimeFieldMapping() *mapping.FieldMapping {
+	dateTimeFieldMapping := bleve.NewDateTimeFieldMapping()
+	return dateTimeFieldMapping
+}
+
 func booleanFieldMapping() *mapping.FieldMapping {
 	booleanFieldMapping := bleve.NewBooleanFieldMapping()
 	return booleanFieldMapping
@@ -61,6 +74,7 @@ func BuildIndexMapping() (indexMapping *mapping.IndexMappingImpl, err error) {
 	}
 
 	excludeFieldMapping := excludeFieldMapping()
+	dateTimeFieldMapping := dateTimeFieldMapping()
 	booleanFieldMapping := booleanFieldMapping()
 	keywordFieldMapping := keywordFieldMapping()
 	textFieldMapping := textFieldMapping()
@@ -96,6 +110,13 @@ func BuildIndexMapping() (indexMapping *mapping.IndexMappingImpl, err error) {
 	resourceMapping.AddFieldMappingsAt("type", keywordFieldMapping)
 	resourceMapping.AddFieldMappingsAt("canonicalUri", keywordFieldMapping)
 	resourceMapping.AddFieldMappingsAt("urn", excludeFieldMapping)
+	resourceMapping.AddFieldMappingsAt(RecordIndexedAtField, dateTimeFieldMapping)
+	resourceMapping.AddFieldMappingsAt(RecordFilenameField, keywordFieldMapping)
+	resourceMapping.AddFieldMappingsAt(RecordTitleField, textFieldMapping)
+	resourceMapping.AddFieldMappingsAt(RecordContentField, htmlFieldMapping)
+	resourceMapping.AddFieldMappingsAt(RecordPathField, keywordFieldMapping)
+	resourceMapping.AddFieldMappingsAt(RecordLanguageField, keywordFieldMapping)
+	resourceMapping.AddFieldMappingsAt(RecordModifiedField, dateTimeFieldMapping)
 
 	// Resource [FSFile]
 	resourceMapping.AddFieldMappingsAt(fmt.Sprintf("%s.path", ResFSFile), keywordFieldMapping)
@@ -105,12 +126,18 @@ func BuildIndexMapping() (indexMapping *mapping.IndexMappingImpl, err error) {
 	resourceMapping.AddFieldMappingsAt(fmt.Sprintf("%s.contents_keywords", ResFSFile), keywordFieldMapping)
 	resourceMapping.AddFieldMappingsAt(fmt.Sprintf("%s.contents_text", ResFSFile), textFieldMapping)
 	resourceMapping.AddFieldMappingsAt(fmt.Sprintf("%s.contents_html", ResFSFile), htmlFieldMapping)
+	resourceMapping.AddFieldMappingsAt(fmt.Sprintf("%s.mtime", ResFSFile), dateTimeFieldMapping)
+	resourceMapping.AddFieldMappingsAt(fmt.Sprintf("%s.ctime", ResFSFile), dateTimeFieldMapping)
 
 	// Resource [WebPage]
+	resourceMapping.AddFieldMappingsAt(fmt.Sprintf("%s.host", ResWebPage), keywordFieldMapping)
 	resourceMapping.AddFieldMappingsAt(fmt.Sprintf("%s.path", ResWebPage), keywordFieldMapping)
 	resourceMapping.AddFieldMappingsAt(fmt.Sprintf("%s.query", ResWebPage), keywordFieldMapping)
 	resourceMapping.AddFieldMappingsAt(fmt.Sprintf("%s.title", ResWebPage), textFieldMapping)
 	resourceMapping.AddFieldMappingsAt(fmt.Sprintf("%s.body", ResWebPage), htmlFieldMapping)
+	resourceMapping.AddFieldMappingsAt(fmt.Sprintf("%s.lastModified", ResWebPage), dateTimeFieldMapping)
+	resourceMapping.AddFieldMappingsAt(fmt.Sprintf("%s.date", ResWebPage), dateTimeFieldMapping)
+	resourceMapping.AddFieldMappingsAt(fmt.Sprintf("%s.publishedAt", ResWebPage), dateTimeFieldMapping)
 
 	indexMapping.AddDocumentMapping(string(RecordSource), sourceMapping)
 	indexMapping.AddDocumentMapping(string(RecordResource), resourceMapping)
@@ -0,0 +1,466 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/query"
+
+	"risp/protocol"
+)
+
+const (
+	defaultQueryLimit     = 10
+	maxQueryLimit         = 1000
+	maxQueryOffset        = 10000
+	defaultQueryFacetSize = 10
+)
+
+var queryFacetFields = map[protocol.QueryFacetField]string{
+	protocol.QueryFacetField_TYPE:      "type",
+	protocol.QueryFacetField_FILETYPE:  fmt.Sprintf("%s.filetype", ResFSFile),
+	protocol.QueryFacetField_HOST:      fmt.Sprintf("%s.host", ResWebPage),
+	protocol.QueryFacetField_SOURCE:    "sourceId",
+	protocol.QueryFacetField_MODIFIED:  RecordModifiedField,
+	protocol.QueryFacetField_INDEXED:   RecordIndexedAtField,
+	protocol.QueryFacetField_PUBLISHED: fmt.Sprintf("%s.publishedAt", ResWebPage),
+}
+
+// dateFacetFields are faceted by date histogram, and filtered by date range
+var dateFacetFields = map[protocol.QueryFacetField]bool{
+	protocol.QueryFacetField_MODIFIED:  true,
+	protocol.QueryFacetField_INDEXED:   true,
+	protocol.QueryFacetField_PUBLISHED: true,
+}
+
+/**
+ * SearchOptions : How Context.Search pages, sorts and highlights its hits
+ */
+
+type SearchOptions struct {
+	HighlightStyle string
+	Limit          int
+	Offset         int
+	SortBy         []string // bleve sort order, e.g. "-_score"
+	Facets         map[protocol.QueryFacetField]*SearchFacet
+	Filters        map[protocol.QueryFacetField][]string
+	Recency        *SearchRecency
+	Explain        bool // break down the score of each hit
+}
+
+type SearchFacet struct {
+	Size     int
+	Interval protocol.QueryDateInterval // of date fields only
+}
+
+/**
+ * SearchRecency : Decays the score of hits by the age of a date field,
+ * halving it every HalfLife
+ */
+
+type SearchRecency struct {
+	Field    string
+	HalfLife time.Duration
+}
+
+func (options *SearchOptions) limit() int {
+	switch true {
+	case options.Limit < 1:
+		return defaultQueryLimit
+	case options.Limit > maxQueryLimit:
+		return maxQueryLimit
+	}
+
+	return options.Limit
+}
+
+func (options *SearchOptions) offset() int {
+	switch true {
+	case options.Offset < 0:
+		return 0
+	case options.Offset > maxQueryOffset:
+		return maxQueryOffset
+	}
+
+	return options.Offset
+}
+
+// UnmarshalQuerySort converts the requested sort into a bleve sort order,
+// ending with the document ID so that pages stay stable between requests
+func UnmarshalQuerySort(sorts []*protocol.QuerySort) (sortBy []string) {
+	if len(sorts) < 1 {
+		return nil
+	}
+
+	sortBy = make([]string, 0, len(sorts)+1)
+
+	for _, sort := range sorts {
+		var (
+			field      string
+			descending bool
+		)
+
+		switch sort.Field {
+		case protocol.QuerySortField_SCORE:
+			field, descending = "_score", true
+		case protocol.QuerySortField_INDEXED_AT:
+			field, descending = RecordIndexedAtField, true
+		case protocol.QuerySortField_CANONICAL_URI:
+			field = "canonicalUri"
+		case protocol.QuerySortField_FILENAME:
+			field = RecordFilenameField
+		default:
+			continue
+		}
+
+		if sort.Descending != nil {
+			descending = *sort.Descending
+		}
+
+		if descending {
+			field = "-" + field
+		}
+
+		sortBy = append(sortBy, field)
+	}
+
+	return append(sortBy, "_id")
+}
+
+func UnmarshalQueryFacets(facets []*protocol.QueryFacetRequest) (searchFacets map[protocol.QueryFacetField]*SearchFacet) {
+	searchFacets = map[protocol.QueryFacetField]*SearchFacet{}
+
+	for _, facet := range facets {
+		if _, isFacetField := queryFacetFields[facet.Field]; !isFacetField {
+			continue
+		}
+
+		searchFacet := &SearchFacet{
+			Size:     defaultQueryFacetSize,
+			Interval: facet.Interval,
+		}
+
+		if facet.Size > 0 {
+			searchFacet.Size = int(facet.Size)
+		}
+
+		searchFacets[facet.Field] = searchFacet
+	}
+
+	return
+}
+
+// UnmarshalQueryFilters collects the filter values per field, checking
+// those of date fields to be dates or date ranges
+func UnmarshalQueryFilters(filters []*protocol.QueryFilter) (values map[protocol.QueryFacetField][]string, err error) {
+	values = map[protocol.QueryFacetField][]string{}
+
+	for _, filter := range filters {
+		if _, isFacetField := queryFacetFields[filter.Field]; !isFacetField || len(filter.Values) < 1 {
+			continue
+		}
+
+		if dateFacetFields[filter.Field] {
+			for _, value := range filter.Values {
+				if _, err = ParseDateRange(value, time.Now()); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		values[filter.Field] = append(values[filter.Field], filter.Values...)
+	}
+
+	return
+}
+
+func UnmarshalQueryRecency(recency *protocol.QueryRecency) (searchRecency *SearchRecency, err error) {
+	if recency == nil {
+		return nil, nil
+	}
+
+	if recency.HalfLife <= 0 {
+		return nil, fmt.Errorf("invalid recency half life %dms, expected a positive duration", recency.HalfLife)
+	}
+
+	field := protocol.QueryFacetField_MODIFIED
+
+	if recency.Field != nil {
+		field = *recency.Field
+	}
+
+	if !dateFacetFields[field] {
+		return nil, fmt.Errorf("invalid recency field '%s', expected a date field", field)
+	}
+
+	return &SearchRecency{
+		Field:    queryFacetFields[field],
+		HalfLife: time.Duration(recency.HalfLife) * time.Millisecond,
+	}, nil
+}
+
+// facetFilterQuery matches any of the values of a facet field, the values
+// of date fields being date ranges
+func facetFilterQuery(field protocol.QueryFacetField, values []string, now time.Time) (query.Query, error) {
+	if !dateFacetFields[field] {
+		return filterQuery(queryFacetFields[field], values), nil
+	}
+
+	filter := bleve.NewDisjunctionQuery()
+
+	for _, value := range values {
+		dateRange, err := ParseDateRange(value, now)
+		if err != nil {
+			return nil, err
+		}
+
+		filter.AddQuery(dateRange.Query(queryFacetFields[field]))
+	}
+
+	return filter, nil
+}
+
+// filterQuery matches any of the exact values of a keyword field
+func filterQuery(field string, values []string) query.Query {
+	filter := bleve.NewDisjunctionQuery()
+
+	for _, value := range values {
+		termQuery := bleve.NewTermQuery(value)
+		termQuery.SetField(field)
+
+		filter.AddQuery(termQuery)
+	}
+
+	return filter
+}
+
+func MarshalQueryFacets(facets search.FacetResults) (facetsProto []*protocol.QueryFacet) {
+	facetsProto = make([]*protocol.QueryFacet, 0, len(facets))
+
+	for name, facet := range facets {
+		facetProto := &protocol.QueryFacet{
+			Field:   protocol.QueryFacetField(protocol.QueryFacetField_value[name]),
+			Total:   int64(facet.Total),
+			Missing: int64(facet.Missing),
+			Other:   int64(facet.Other),
+			Buckets: make([]*protocol.QueryFacetBucket, 0),
+		}
+
+		if facet.Terms != nil {
+			for _, term := range facet.Terms.Terms() {
+				facetProto.Buckets = append(facetProto.Buckets, &protocol.QueryFacetBucket{
+					Value: term.Term,
+					Count: int64(term.Count),
+				})
+			}
+		}
+
+		if len(facet.DateRanges) > 0 {
+			for _, dateRange := range facet.DateRanges {
+				facetProto.Buckets = append(facetProto.Buckets, &protocol.QueryFacetBucket{
+					Value: dateRange.Name,
+					Count: int64(dateRange.Count),
+				})
+			}
+
+			// the newest bucket first, as the bucket names are sortable dates
+			sort.Slice(facetProto.Buckets, func(i, j int) bool {
+				return facetProto.Buckets[i].Value > facetProto.Buckets[j].Value
+			})
+		}
+
+		facetsProto = append(facetsProto, facetProto)
+	}
+
+	sort.Slice(facetsProto, func(i, j int) bool {
+		return facetsProto[i].Field < facetsProto[j].Field
+	})
+
+	return
+}
+
+// UnmarshalSearchQuery builds the query of a request, preferring its
+// structured query over its query string
+func UnmarshalSearchQuery(request *protocol.QueryRequest) (searchQuery query.Query, err error) {
+	if request.Query != nil {
+		searchQuery, err = UnmarshalQuery(request.Query)
+	} else if len(request.Value) < 1 {
+		return nil, fmt.Errorf("empty query")
+	} else {
+		searchQuery = boostQueryFields(
+			bleve.NewQueryStringQuery(request.Value),
+			queryStringTerms(request.Value),
+		)
+	}
+
+	if err != nil {
+		return
+	}
+
+	if validatableQuery, isValidatable := searchQuery.(query.ValidatableQuery); isValidatable {
+		if err = validatableQuery.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return
+}
+
+func UnmarshalQuery(queryProto *protocol.Query) (searchQuery query.Query, err error) {
+	if queryProto == nil {
+		return nil, fmt.Errorf("empty query")
+	}
+
+	switch item := queryProto.Query.(type) {
+	case *protocol.Query_Match:
+		matchQuery := bleve.NewMatchQuery(item.Match.Value)
+		matchQuery.SetField(item.Match.Field)
+		matchQuery.SetFuzziness(int(item.Match.Fuzziness))
+
+		if item.Match.AllTerms {
+			matchQuery.SetOperator(query.MatchQueryOperatorAnd)
+		}
+
+		searchQuery = matchQuery
+	case *protocol.Query_Phrase:
+		phraseQuery := bleve.NewMatchPhraseQuery(item.Phrase.Value)
+		phraseQuery.SetField(item.Phrase.Field)
+
+		searchQuery = phraseQuery
+	case *protocol.Query_Prefix:
+		prefixQuery := bleve.NewPrefixQuery(item.Prefix.Prefix)
+		prefixQuery.SetField(item.Prefix.Field)
+
+		searchQuery = prefixQuery
+	case *protocol.Query_Fuzzy:
+		fuzzyQuery := bleve.NewFuzzyQuery(item.Fuzzy.Term)
+		fuzzyQuery.SetField(item.Fuzzy.Field)
+		fuzzyQuery.SetPrefix(int(item.Fuzzy.Prefix))
+
+		if item.Fuzzy.Fuzziness > 0 {
+			fuzzyQuery.SetFuzziness(int(item.Fuzzy.Fuzziness))
+		}
+
+		searchQuery = fuzzyQuery
+	case *protocol.Query_Wildcard:
+		wildcardQuery := bleve.NewWildcardQuery(item.Wildcard.Wildcard)
+		wildcardQuery.SetField(item.Wildcard.Field)
+
+		searchQuery = wildcardQuery
+	case *protocol.Query_Regexp:
+		if _, err = regexp.Compile(item.Regexp.Regexp); err != nil {
+			return nil, fmt.Errorf("invalid regexp '%s': %s", item.Regexp.Regexp, err)
+		}
+
+		regexpQuery := bleve.NewRegexpQuery(item.Regexp.Regexp)
+		regexpQuery.SetField(item.Regexp.Field)
+
+		searchQuery = regexpQuery
+	case *protocol.Query_Term:
+		termQuery := bleve.NewTermQuery(item.Term.Term)
+		termQuery.SetField(item.Term.Field)
+
+		searchQuery = termQuery
+	case *protocol.Query_NumericRange:
+		numericRangeQuery := bleve.NewNumericRangeInclusiveQuery(
+			item.NumericRange.Min,
+			item.NumericRange.Max,
+			item.NumericRange.InclusiveMin,
+			item.NumericRange.InclusiveMax,
+		)
+		numericRangeQuery.SetField(item.NumericRange.Field)
+
+		searchQuery = numericRangeQuery
+	case *protocol.Query_DateRange:
+		var start, end time.Time
+
+		if item.DateRange.Start != nil {
+			start = time.UnixMilli(*item.DateRange.Start)
+		}
+
+		if item.DateRange.End != nil {
+			end = time.UnixMilli(*item.DateRange.End)
+		}
+
+		dateRangeQuery := bleve.NewDateRangeInclusiveQuery(
+			start,
+			end,
+			item.DateRange.InclusiveStart,
+			item.DateRange.InclusiveEnd,
+		)
+		dateRangeQuery.SetField(item.DateRange.Field)
+
+		searchQuery = dateRangeQuery
+	case *protocol.Query_Boolean:
+		var must, should, mustNot []query.Query
+
+		if must, err = unmarshalQueries(item.Boolean.Must); err != nil {
+			return
+		}
+
+		if should, err = unmarshalQueries(item.Boolean.Should); err != nil {
+			return
+		}
+
+		if mustNot, err = unmarshalQueries(item.Boolean.MustNot); err != nil {
+			return
+		}
+
+		booleanQuery := query.NewBooleanQuery(must, should, mustNot)
+
+		if len(should) > 0 {
+			booleanQuery.SetMinShould(float64(item.Boolean.MinShould))
+		}
+
+		searchQuery = booleanQuery
+	default:
+		return nil, fmt.Errorf("empty query")
+	}
+
+	if queryProto.Boost != nil {
+		if boostableQuery, isBoostable := searchQuery.(query.BoostableQuery); isBoostable {
+			boostableQuery.SetBoost(*queryProto.Boost)
+		}
+	}
+
+	return
+}
+
+func unmarshalQueries(queriesProto []*protocol.Query) (queries []query.Query, err error) {
+	queries = make([]query.Query, 0, len(queriesProto))
+
+	for _, queryProto := range queriesProto {
+		var searchQuery query.Query
+
+		if searchQuery, err = UnmarshalQuery(queryProto); err != nil {
+			return nil, err
+		}
+
+		queries = append(queries, searchQuery)
+	}
+
+	return
+}
+
+// MarshalQueryExplanation converts the score breakdown of a hit, if any
+func MarshalQueryExplanation(explanation *search.Explanation) *protocol.QueryExplanation {
+	if explanation == nil {
+		return nil
+	}
+
+	explanationProto := &protocol.QueryExplanation{
+		Value:    explanation.Value,
+		Message:  explanation.Message,
+		Children: make([]*protocol.QueryExplanation, 0, len(explanation.Children)),
+	}
+
+	for _, child := range explanation.Children {
+		explanationProto.Children = append(explanationProto.Children, MarshalQueryExplanation(child))
+	}
+
+	return explanationProto
+}
@@ -0,0 +1,296 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"github.com/necessitates/clover"
+)
+
+const (
+	defaultWeight = 1.0
+	maxWeight     = 100.0
+	// rescoreWindow is how many of the top hits are rescored by weight and
+	// recency, so that a heavy source or a fresh resource may lift a hit from
+	// beyond the requested page
+	rescoreWindow = 100
+	// minRecencyFactor is the floor of the recency decay, which undated hits
+	// weigh as well
+	minRecencyFactor = 0.1
+)
+
+// queryFieldBoosts weigh matches in a resource's title and filename over
+// matches anywhere else in it
+var queryFieldBoosts = map[string]float64{
+	RecordTitleField:    3,
+	RecordFilenameField: 2,
+}
+
+// boostQueryFields adds the boosted field matches of the given terms as
+// optional clauses, raising the score of hits without widening the results;
+// as filenames are indexed whole, each term matches the filenames it begins
+func boostQueryFields(searchQuery query.Query, terms []string) query.Query {
+	if len(terms) < 1 {
+		return searchQuery
+	}
+
+	should := make([]query.Query, 0, len(terms)+1)
+
+	titleQuery := bleve.NewMatchQuery(strings.Join(terms, " "))
+	titleQuery.SetField(RecordTitleField)
+	titleQuery.SetBoost(queryFieldBoosts[RecordTitleField])
+	should = append(should, titleQuery)
+
+	for _, term := range terms {
+		filenameQuery := bleve.NewPrefixQuery(term)
+		filenameQuery.SetField(RecordFilenameField)
+		filenameQuery.SetBoost(queryFieldBoosts[RecordFilenameField])
+		should = append(should, filenameQuery)
+	}
+
+	return query.NewBooleanQuery([]query.Query{searchQuery}, should, nil)
+}
+
+// queryStringTerms keeps the plain terms of a query string, leaving out the
+// excluded ones and those aimed at a field of their own
+func queryStringTerms(value string) (terms []string) {
+	terms = make([]string, 0)
+
+	for _, term := range strings.Fields(value) {
+		term = strings.TrimPrefix(term, "+")
+
+		if strings.HasPrefix(term, "-") || strings.Contains(term, ":") {
+			continue
+		}
+
+		if term = strings.Trim(term, `"`); term != "" {
+			terms = append(terms, term)
+		}
+	}
+
+	return
+}
+
+func unmarshalWeight(value interface{}) (weight float64, isWeight bool) {
+	switch item := value.(type) {
+	case float64:
+		weight = item
+	case int64:
+		weight = float64(item)
+	case uint64:
+		weight = float64(item)
+	default:
+		return 0, false
+	}
+
+	return weight, weight > 0
+}
+
+func validateWeight(weight float64) error {
+	if math.IsNaN(weight) || weight <= 0 || weight > maxWeight {
+		return fmt.Errorf("invalid weight %v, expected a number above 0 and up to %v", weight, maxWeight)
+	}
+
+	return nil
+}
+
+// getSourceWeights maps the URNs of the context's sources to their weights,
+// leaving out the sources of default weight
+func (context *Context) getSourceWeights() (weights map[string]float64, err error) {
+	var documents []*clover.Document
+
+	if documents, err = context.engine.database.Query(ColSources).Where(
+		clover.Field("contextId").Eq(context.ID).And(clover.Field("weight").Exists()),
+	).FindAll(); err != nil {
+		return
+	}
+
+	weights = map[string]float64{}
+
+	for _, document := range documents {
+		urn, isString := document.Get("urn").(string)
+		if !isString {
+			continue
+		}
+
+		if weight, isWeight := unmarshalWeight(document.Get("weight")); isWeight && weight != defaultWeight {
+			weights[urn] = weight
+		}
+	}
+
+	return
+}
+
+/**
+ * searchRanking : The context and source weights and the recency decay of a
+ * search, multiplying the score of every hit once bleve ranked them
+ */
+
+type searchRanking struct {
+	contexts map[string]float64
+	sources  map[string]float64
+	recency  *SearchRecency
+	now      time.Time
+}
+
+func loadSearchRanking(contexts []*Context, recency *SearchRecency) (ranking *searchRanking, err error) {
+	ranking = &searchRanking{
+		contexts: map[string]float64{},
+		sources:  map[string]float64{},
+		recency:  recency,
+		now:      time.Now(),
+	}
+
+	for _, context := range contexts {
+		var sourceWeights map[string]float64
+
+		if weight := context.getWeight(); weight != defaultWeight {
+			ranking.contexts[context.ID] = weight
+		}
+
+		if sourceWeights, err = context.getSourceWeights(); err != nil {
+			return nil, err
+		}
+
+		for urn, weight := range sourceWeights {
+			ranking.sources[urn] = weight
+		}
+	}
+
+	return
+}
+
+func (ranking *searchRanking) isEmpty() bool {
+	return len(ranking.contexts) < 1 && len(ranking.sources) < 1 && ranking.recency == nil
+}
+
+// fields lists the stored fields the ranking reads off the hits
+func (ranking *searchRanking) fields() []string {
+	if ranking.recency == nil {
+		return nil
+	}
+
+	return []string{ranking.recency.Field}
+}
+
+func (ranking *searchRanking) of(hit *SearchResultHit) float64 {
+	weight := defaultWeight
+
+	if contextWeight, hasWeight := ranking.contexts[hit.ContextID]; hasWeight {
+		weight *= contextWeight
+	}
+
+	if sourceWeight, hasWeight := ranking.sources[hit.Resource.SourceURN()]; hasWeight {
+		weight *= sourceWeight
+	}
+
+	if ranking.recency != nil {
+		weight *= ranking.recencyOf(hit)
+	}
+
+	return weight
+}
+
+func (ranking *searchRanking) recencyOf(hit *SearchResultHit) float64 {
+	value, isString := hit.fields[ranking.recency.Field].(string)
+	if !isString {
+		return minRecencyFactor
+	}
+
+	date, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return minRecencyFactor
+	}
+
+	age := ranking.now.Sub(date)
+	if age < 0 {
+		return 1
+	}
+
+	return math.Max(minRecencyFactor, math.Pow(0.5, float64(age)/float64(ranking.recency.HalfLife)))
+}
+
+// explain breaks the rescored score of a hit down into its index score and
+// the weights it is multiplied by
+func (ranking *searchRanking) explain(hit *SearchResultHit) *search.Explanation {
+	children := []*search.Explanation{hit.Explanation}
+
+	if contextWeight, hasWeight := ranking.contexts[hit.ContextID]; hasWeight {
+		children = append(children, &search.Explanation{
+			Value:   contextWeight,
+			Message: fmt.Sprintf("weight of context %s", hit.ContextID),
+		})
+	}
+
+	if sourceWeight, hasWeight := ranking.sources[hit.Resource.SourceURN()]; hasWeight {
+		children = append(children, &search.Explanation{
+			Value:   sourceWeight,
+			Message: fmt.Sprintf("weight of source %s", hit.Resource.SourceURN()),
+		})
+	}
+
+	if ranking.recency != nil {
+		children = append(children, &search.Explanation{
+			Value:   ranking.recencyOf(hit),
+			Message: fmt.Sprintf("recency of %s, halving every %s", ranking.recency.Field, ranking.recency.HalfLife),
+		})
+	}
+
+	return &search.Explanation{
+		Value:    hit.Score * ranking.of(hit),
+		Message:  "product of:",
+		Children: children,
+	}
+}
+
+// rescore weighs the hits of the rescore window, the ones beyond it keeping
+// their order, and cuts the requested page out of them
+func (ranking *searchRanking) rescore(result *SearchResult, offset, limit int) {
+	window := result.Hits
+
+	if len(window) > rescoreWindow {
+		window = window[:rescoreWindow]
+	}
+
+	for _, hit := range window {
+		if hit.Explanation != nil {
+			hit.Explanation = ranking.explain(hit)
+		}
+
+		hit.Score *= ranking.of(hit)
+	}
+
+	sort.SliceStable(window, func(i, j int) bool {
+		return window[i].Score > window[j].Score
+	})
+
+	result.MaxScore = 0
+
+	for _, hit := range result.Hits {
+		result.MaxScore = math.Max(result.MaxScore, hit.Score)
+	}
+
+	end := offset + limit
+
+	if end > len(result.Hits) {
+		end = len(result.Hits)
+	}
+
+	if offset > end {
+		offset = end
+	}
+
+	result.Hits = result.Hits[offset:end]
+}
+
+// isRankedByScore tells whether a sort order ranks hits by score, which is
+// when the ranking matters
+func isRankedByScore(sortBy []string) bool {
+	return len(sortBy) < 1 || sortBy[0] == "-_score"
+}
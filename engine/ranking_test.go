@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+// TestRankedPaging pages through hits rescored by source weight, the pages
+// within and beyond the rescore window together listing every hit once
+func TestRankedPaging(t *testing.T) {
+	engine, defaultContext := newTestEngine(t)
+
+	for i, weight := range []float64{1, 3} {
+		_, source := indexTestSource(t, engine, defaultContext, newTestSourceDir(t, 80))
+
+		if err := defaultContext.UpdateSourceWeight(source, weight); err != nil {
+			t.Fatalf("weighing source %d: %+v", i, err)
+		}
+	}
+
+	var (
+		searchQuery = bleve.NewMatchQuery("resource")
+		seen        = map[string]bool{}
+		total       uint64
+	)
+
+	for offset := 0; offset == 0 || uint64(offset) < total; offset += 7 {
+		result, err := defaultContext.Search(searchQuery, &SearchOptions{Limit: 7, Offset: offset})
+		if err != nil {
+			t.Fatalf("searching from %d: %+v", offset, err)
+		}
+
+		total = result.Total
+
+		for _, hit := range result.Hits {
+			resourceID := *hit.Resource.ID()
+
+			if seen[resourceID] {
+				t.Errorf("hit %s listed again from offset %d", resourceID, offset)
+			}
+
+			seen[resourceID] = true
+		}
+	}
+
+	if total != 160 || len(seen) != 160 {
+		t.Errorf("listed %d hits of %d, want 160", len(seen), total)
+	}
+}
@@ -1,5 +1,9 @@
 package engine
 
+import (
+	"time"
+)
+
 /**
  * Record : An interface - a referenceable type - for the Bleve "single-table style" store's record
  */
@@ -12,6 +16,16 @@ const (
 	RecordResource  RecordType = "resource"
 )
 
+const (
+	RecordIndexedAtField string = "indexedAt"
+	RecordFilenameField  string = "filename"
+	RecordTitleField     string = "title"
+	RecordContentField   string = "content"
+	RecordPathField      string = "path"
+	RecordLanguageField  string = "language"
+	RecordModifiedField  string = "modified"
+)
+
 type Record map[string]interface{}
 
 func (record Record) SetAll(entries map[string]interface{}) Record {
@@ -22,8 +36,34 @@ func (record Record) SetAll(entries map[string]interface{}) Record {
 	return record
 }
 
+// SetType stores the type as a plain string, as bleve only resolves the
+// document mapping of string type fields
 func (record Record) SetType(recordType RecordType) Record {
 	return record.SetAll(map[string]interface{}{
-		RecordTypeField: recordType,
+		RecordTypeField: string(recordType),
 	})
 }
+
+/**
+ * RecordFields : The fields every resource record fills in alike, so that
+ * queries and highlights need not know the fields of each resource type
+ */
+
+type RecordFields struct {
+	Title    string
+	Content  string
+	Path     string
+	Language string
+	Modified time.Time
+}
+
+func (fields *RecordFields) MarshalRecord(record Record) {
+	record[RecordTitleField] = fields.Title
+	record[RecordContentField] = fields.Content
+	record[RecordPathField] = fields.Path
+	record[RecordLanguageField] = fields.Language
+
+	if !fields.Modified.IsZero() {
+		record[RecordModifiedField] = fields.Modified
+	}
+}
@@ -0,0 +1,209 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+const (
+	// maxRelatedTerms is how many of a resource's most significant terms
+	// relate other resources to it
+	maxRelatedTerms = 25
+	// maxRelatedCandidates bounds the terms, by frequency within the
+	// resource, whose document frequency is looked up
+	maxRelatedCandidates = 200
+	// maxRelatedTermShare leaves out the terms found in more than this share
+	// of the documents, being too common to relate anything
+	maxRelatedTermShare = 0.5
+)
+
+// relatedFields are the unified fields read for significant terms
+var relatedFields = []string{RecordTitleField, RecordContentField}
+
+/**
+ * relatedTerm : A term of a resource, weighing by its frequency within the
+ * resource and its rarity within the index
+ */
+
+type relatedTerm struct {
+	field  string
+	term   string
+	count  int
+	weight float64
+}
+
+// GetRelatedResources finds the resources sharing the most significant terms
+// of the given one, which is left out of the result
+func (context *Context) GetRelatedResources(resourceID string, limit int) (result *SearchResult, terms []string, err error) {
+	var relatedTerms []*relatedTerm
+
+	if _, err = context.GetResource(resourceID); err != nil {
+		return
+	}
+
+	if relatedTerms, err = context.significantTerms(resourceID); err != nil {
+		return
+	}
+
+	terms = make([]string, 0, len(relatedTerms))
+
+	if len(relatedTerms) < 1 {
+		return &SearchResult{Hits: make([]*SearchResultHit, 0)}, terms, nil
+	}
+
+	disjunctionQuery := bleve.NewDisjunctionQuery()
+
+	for _, relatedTerm := range relatedTerms {
+		termQuery := bleve.NewTermQuery(relatedTerm.term)
+		termQuery.SetField(relatedTerm.field)
+		termQuery.SetBoost(relatedTerm.weight)
+
+		disjunctionQuery.AddQuery(termQuery)
+		terms = append(terms, relatedTerm.term)
+	}
+
+	relatedQuery := query.NewBooleanQuery(
+		[]query.Query{disjunctionQuery},
+		nil,
+		[]query.Query{bleve.NewDocIDQuery([]string{resourceID})},
+	)
+
+	result, err = context.Search(relatedQuery, &SearchOptions{
+		Limit: limit,
+	})
+
+	return
+}
+
+// significantTerms analyzes the stored fields of a resource as they were
+// indexed, and weighs their terms by tf-idf
+func (context *Context) significantTerms(resourceID string) (relatedTerms []*relatedTerm, err error) {
+	var (
+		searchResult *bleve.SearchResult
+		docCount     uint64
+		indexMapping = context.index.Mapping()
+	)
+
+	searchRequest := bleve.NewSearchRequest(bleve.NewDocIDQuery([]string{resourceID}))
+	searchRequest.Fields = relatedFields
+
+	if searchResult, err = context.index.Search(searchRequest); err != nil {
+		return
+	}
+
+	if len(searchResult.Hits) < 1 {
+		return nil, fmt.Errorf("%w: '%s'", errResourceNotFound, resourceID)
+	}
+
+	if docCount, err = context.index.DocCount(); err != nil {
+		return
+	}
+
+	candidates := make([]*relatedTerm, 0)
+
+	for _, field := range relatedFields {
+		value, isString := searchResult.Hits[0].Fields[field].(string)
+		if !isString || value == "" {
+			continue
+		}
+
+		analyzer := indexMapping.AnalyzerNamed(indexMapping.AnalyzerNameForPath(field))
+		if analyzer == nil {
+			continue
+		}
+
+		counts := map[string]int{}
+
+		for _, token := range analyzer.Analyze([]byte(value)) {
+			if term := string(token.Term); isSignificantTerm(term) {
+				counts[term]++
+			}
+		}
+
+		for term, count := range counts {
+			candidates = append(candidates, &relatedTerm{
+				field: field,
+				term:  term,
+				count: count,
+			})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].count != candidates[j].count {
+			return candidates[i].count > candidates[j].count
+		}
+
+		return candidates[i].term < candidates[j].term
+	})
+
+	if len(candidates) > maxRelatedCandidates {
+		candidates = candidates[:maxRelatedCandidates]
+	}
+
+	relatedTerms = make([]*relatedTerm, 0, len(candidates))
+
+	for _, candidate := range candidates {
+		var documentCount uint64
+
+		if documentCount, err = context.termDocumentCount(candidate.field, candidate.term); err != nil {
+			return
+		}
+
+		// a term of no other document relates nothing
+		if documentCount < 2 || float64(documentCount) > maxRelatedTermShare*float64(docCount) {
+			continue
+		}
+
+		candidate.weight = (1 + math.Log(float64(candidate.count))) * math.Log(1+float64(docCount)/float64(documentCount))
+		relatedTerms = append(relatedTerms, candidate)
+	}
+
+	sort.Slice(relatedTerms, func(i, j int) bool {
+		return relatedTerms[i].weight > relatedTerms[j].weight
+	})
+
+	if len(relatedTerms) > maxRelatedTerms {
+		relatedTerms = relatedTerms[:maxRelatedTerms]
+	}
+
+	return
+}
+
+// termDocumentCount counts the documents holding the term in the field
+func (context *Context) termDocumentCount(field string, term string) (count uint64, err error) {
+	fieldDict, err := context.index.FieldDictPrefix(field, []byte(term))
+	if err != nil {
+		return
+	}
+
+	defer fieldDict.Close()
+
+	entry, err := fieldDict.Next()
+	if err != nil || entry == nil || entry.Term != term {
+		return 0, err
+	}
+
+	return entry.Count, nil
+}
+
+// isSignificantTerm leaves out the terms too short or numeric to carry
+// meaning of their own
+func isSignificantTerm(term string) bool {
+	if utf8.RuneCountInString(term) < 3 {
+		return false
+	}
+
+	for _, char := range term {
+		if char < '0' || char > '9' {
+			return true
+		}
+	}
+
+	return false
+}
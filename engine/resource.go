@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/necessitates/clover"
 
@@ -28,6 +29,7 @@ type Resource interface {
 	MarshalMap() map[string]interface{}
 	MarshalRecord(Record)
 	MarshalProtocol() *protocol.Resource
+	RecordFields() *RecordFields
 
 	UnmarshalMap(map[string]interface{}) error
 	UnmarshalDBDocument(*clover.Document) error
@@ -162,6 +164,16 @@ func (resourceBase *ResourceBase) MarshalMap() (value map[string]interface{}) {
 
 func (resourceBase *ResourceBase) MarshalRecord(record Record) {
 	record.SetAll(resourceBase.MarshalMap())
+	record[RecordIndexedAtField] = time.Now()
+}
+
+// RecordFields is the fallback of resource types knowing no better title or
+// content than their canonical URI
+func (resourceBase *ResourceBase) RecordFields() *RecordFields {
+	return &RecordFields{
+		Title: resourceBase.canonicalURI,
+		Path:  resourceBase.canonicalURI,
+	}
 }
 
 func (resourceBase *ResourceBase) MarshalProtocol() (resourceProto *protocol.Resource) {
@@ -2,11 +2,14 @@ package engine
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/necessitates/clover"
 	"golang.org/x/net/html"
@@ -25,6 +28,12 @@ type ResourceFSFile struct {
 	contents_keywords string
 	contents_text     string
 	contents_html     string
+	title             string
+	language          string
+	mtime             time.Time
+	ctime             time.Time
+	size              int64
+	hash              string // of the contents last read
 	skipReadOnIndex   bool
 }
 
@@ -65,12 +74,19 @@ func (resourceFSFile *ResourceFSFile) MarshalMap() (value map[string]interface{}
 		"isDot":    resourceFSFile.IsDot,
 	}
 
+	if resourceFSFile.hash != "" {
+		value[ResFSFile.String()].(map[string]interface{})["mtime"] = resourceFSFile.mtime
+		value[ResFSFile.String()].(map[string]interface{})["size"] = resourceFSFile.size
+		value[ResFSFile.String()].(map[string]interface{})["hash"] = resourceFSFile.hash
+	}
+
 	return
 }
 
 func (resourceFSFile *ResourceFSFile) MarshalRecord(record Record) {
 	resourceFSFile.ResourceBase.MarshalRecord(record)
 
+	record[RecordFilenameField] = resourceFSFile.Filename
 	record[ResFSFile.String()] = map[string]interface{}{
 		"path":              resourceFSFile.Path,
 		"filename":          resourceFSFile.Filename,
@@ -80,6 +96,33 @@ func (resourceFSFile *ResourceFSFile) MarshalRecord(record Record) {
 		"contents_text":     resourceFSFile.contents_text,
 		"contents_html":     resourceFSFile.contents_html,
 	}
+
+	if !resourceFSFile.mtime.IsZero() {
+		record[ResFSFile.String()].(map[string]interface{})["mtime"] = resourceFSFile.mtime
+		record[ResFSFile.String()].(map[string]interface{})["ctime"] = resourceFSFile.ctime
+	}
+
+	resourceFSFile.RecordFields().MarshalRecord(record)
+}
+
+func (resourceFSFile *ResourceFSFile) RecordFields() *RecordFields {
+	recordFields := &RecordFields{
+		Title:    resourceFSFile.title,
+		Content:  resourceFSFile.contents_text,
+		Path:     resourceFSFile.Path,
+		Language: resourceFSFile.language,
+		Modified: resourceFSFile.mtime,
+	}
+
+	if recordFields.Title == "" {
+		recordFields.Title = resourceFSFile.Filename
+	}
+
+	if resourceFSFile.contents_html != "" {
+		recordFields.Content = resourceFSFile.contents_html
+	}
+
+	return recordFields
 }
 
 func (resourceFSFile *ResourceFSFile) MarshalProtocol() *protocol.Resource {
@@ -140,10 +183,37 @@ func (resourceFSFile *ResourceFSFile) UnmarshalDBDocument(document *clover.Docum
 	unmarshalString(&resourceFSFile.Filename, "filename")
 	unmarshalString(&resourceFSFile.Filetype, "filetype")
 	unmarshalBool(&resourceFSFile.IsDot, "isDot")
+	unmarshalString(&resourceFSFile.hash, "hash")
+
+	if mtime, isTime := document.Get(fmt.Sprintf("%s.mtime", ResFSFile)).(time.Time); isTime {
+		resourceFSFile.mtime = mtime
+	}
+
+	if size, isInt64 := document.Get(fmt.Sprintf("%s.size", ResFSFile)).(int64); isInt64 {
+		resourceFSFile.size = size
+	}
 
 	return nil
 }
 
+// isUnchanged tells whether the file has the modification time and size it
+// had when last indexed
+func (resourceFSFile *ResourceFSFile) isUnchanged(fileStat os.FileInfo) bool {
+	return resourceFSFile.hash != "" &&
+		resourceFSFile.mtime.Equal(fileStat.ModTime()) &&
+		resourceFSFile.size == fileStat.Size()
+}
+
+// updateFileState records the modification time, size and content hash of
+// the file last read, for the next crawl to skip the file if unchanged
+func (resourceFSFile *ResourceFSFile) updateFileState(database *clover.DB) error {
+	return database.Query(ColResources).UpdateById(*resourceFSFile.ID(), map[string]interface{}{
+		fmt.Sprintf("%s.mtime", ResFSFile): resourceFSFile.mtime,
+		fmt.Sprintf("%s.size", ResFSFile):  resourceFSFile.size,
+		fmt.Sprintf("%s.hash", ResFSFile):  resourceFSFile.hash,
+	})
+}
+
 func (resourceFSFile *ResourceFSFile) Index(adapter Adapter) (err error) {
 	if adapter.Type() != AdapterTypeFS {
 		return fmt.Errorf("invalid adapter '%s': ResourceWebPage expects adapter type '%s'", adapter.Type(), AdapterTypeWeb)
@@ -190,6 +260,9 @@ func (resourceFSFile *ResourceFSFile) parseFile(adapter Adapter, data []byte) (e
 			return
 		}
 
+		resourceFSFile.title = findHTMLTitle(webpageNode)
+		resourceFSFile.language = findHTMLLanguage(webpageNode)
+
 		if buffer, err = sanitizeHTMLDocument(webpageNode); err != nil {
 			return
 		}
@@ -207,5 +280,22 @@ func (resourceFSFile *ResourceFSFile) readFile(adapter Adapter) (data []byte, er
 		return data, err
 	}
 
-	return os.ReadFile(resourceURI.Path)
+	var resourceStat os.FileInfo
+
+	if resourceStat, err = os.Stat(resourceURI.Path); err != nil {
+		return
+	}
+
+	resourceFSFile.mtime = resourceStat.ModTime()
+	resourceFSFile.ctime = fileChangeTime(resourceStat)
+	resourceFSFile.size = resourceStat.Size()
+
+	if data, err = os.ReadFile(resourceURI.Path); err != nil {
+		return
+	}
+
+	contentHash := sha256.Sum256(data)
+	resourceFSFile.hash = hex.EncodeToString(contentHash[:])
+
+	return
 }
@@ -8,7 +8,9 @@ import (
 	"mime"
 	"net/http"
 	"net/url"
+	"path"
 	"strings"
+	"time"
 
 	"github.com/necessitates/clover"
 	"golang.org/x/net/html"
@@ -24,6 +26,11 @@ type ResourceWebPage struct {
 	Query            string
 	Title            string
 	body             string
+	language         string
+	lastModified     time.Time
+	date             time.Time
+	publishedAt      time.Time
+	size             int64
 	skipFetchOnIndex bool
 }
 
@@ -61,6 +68,7 @@ func (resourceWebPage *ResourceWebPage) MarshalMap() (value map[string]interface
 		"path":  resourceWebPage.Path,
 		"query": resourceWebPage.Query,
 		"title": resourceWebPage.Title,
+		"size":  resourceWebPage.size,
 	}
 
 	return
@@ -69,12 +77,53 @@ func (resourceWebPage *ResourceWebPage) MarshalMap() (value map[string]interface
 func (resourceWebPage *ResourceWebPage) MarshalRecord(record Record) {
 	resourceWebPage.ResourceBase.MarshalRecord(record)
 
+	host := ""
+	if resourceWebPage.source != nil {
+		if adapterDataWeb, isAdapterDataWeb := resourceWebPage.source.AdapterData.(*AdapterDataWeb); isAdapterDataWeb {
+			host = adapterDataWeb.Host
+		}
+	}
+
+	record[RecordFilenameField] = strings.TrimPrefix(path.Base(resourceWebPage.Path), "/")
 	record[ResWebPage.String()] = map[string]interface{}{
+		"host":  host,
 		"path":  resourceWebPage.Path,
 		"query": resourceWebPage.Query,
 		"title": resourceWebPage.Title,
 		"body":  resourceWebPage.body,
 	}
+
+	for key, value := range map[string]time.Time{
+		"lastModified": resourceWebPage.lastModified,
+		"date":         resourceWebPage.date,
+		"publishedAt":  resourceWebPage.publishedAt,
+	} {
+		if !value.IsZero() {
+			record[ResWebPage.String()].(map[string]interface{})[key] = value
+		}
+	}
+
+	resourceWebPage.RecordFields().MarshalRecord(record)
+}
+
+func (resourceWebPage *ResourceWebPage) RecordFields() *RecordFields {
+	recordFields := &RecordFields{
+		Title:    resourceWebPage.Title,
+		Content:  resourceWebPage.body,
+		Path:     resourceWebPage.Path,
+		Language: resourceWebPage.language,
+		Modified: resourceWebPage.lastModified,
+	}
+
+	if recordFields.Title == "" {
+		recordFields.Title = resourceWebPage.CanonicalURI()
+	}
+
+	if recordFields.Modified.IsZero() {
+		recordFields.Modified = resourceWebPage.publishedAt
+	}
+
+	return recordFields
 }
 
 func (resourceWebPage *ResourceWebPage) MarshalProtocol() *protocol.Resource {
@@ -122,6 +171,10 @@ func (resourceWebPage *ResourceWebPage) UnmarshalDBDocument(document *clover.Doc
 	unmarshalString(&resourceWebPage.Query, "query")
 	unmarshalString(&resourceWebPage.Title, "title")
 
+	if size, isInt64 := document.Get(fmt.Sprintf("%s.size", ResWebPage)).(int64); isInt64 {
+		resourceWebPage.size = size
+	}
+
 	return nil
 }
 
@@ -141,6 +194,10 @@ func (resourceWebPage *ResourceWebPage) Index(adapter Adapter) (err error) {
 			return
 		}
 
+		defer response.Body.Close()
+
+		resourceWebPage.parseResponseHeaders(response)
+
 		if err = resourceWebPage.parseHTML(response.Body); err != nil {
 			return
 		}
@@ -167,10 +224,14 @@ func (resourceWebPage *ResourceWebPage) parseHTML(reader io.Reader) (err error)
 		return
 	}
 
-	if titleNode, hasTitleNode := findHTMLNode(webpageNode, func(node *html.Node) bool {
-		return node.Type == html.ElementNode && node.Data == "title"
-	}); hasTitleNode {
-		resourceWebPage.Title = titleNode.FirstChild.Data
+	resourceWebPage.Title = findHTMLTitle(webpageNode)
+
+	if language := findHTMLLanguage(webpageNode); language != "" {
+		resourceWebPage.language = language
+	}
+
+	if publishedAt, err := parseHTMLTime(findHTMLMeta(webpageNode, "article:published_time")); err == nil {
+		resourceWebPage.publishedAt = publishedAt
 	}
 
 	if buffer, err = sanitizeHTMLDocument(webpageNode); err != nil {
@@ -182,6 +243,22 @@ func (resourceWebPage *ResourceWebPage) parseHTML(reader io.Reader) (err error)
 	return
 }
 
+// parseResponseHeaders reads the times and the language of the page; a lang
+// attribute found later by parseHTML takes precedence
+func (resourceWebPage *ResourceWebPage) parseResponseHeaders(response *http.Response) {
+	if lastModified, err := http.ParseTime(response.Header.Get("Last-Modified")); err == nil {
+		resourceWebPage.lastModified = lastModified
+	}
+
+	if date, err := http.ParseTime(response.Header.Get("Date")); err == nil {
+		resourceWebPage.date = date
+	}
+
+	if language := strings.Split(response.Header.Get("Content-Language"), ",")[0]; language != "" {
+		resourceWebPage.language = strings.ToLower(strings.TrimSpace(language))
+	}
+}
+
 func (resourceWebPage *ResourceWebPage) httpGET(adapter Adapter) (response *http.Response, err error) {
 	var (
 		contentType  string
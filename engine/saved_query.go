@@ -0,0 +1,313 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/blevesearch/bleve/v2/search/query"
+	"github.com/necessitates/clover"
+
+	"risp/protocol"
+)
+
+// maxSavedQueryMatches bounds the matches, the best scored ones, a saved
+// query keeps track of
+const maxSavedQueryMatches = maxQueryLimit
+
+var errSavedQueryNotFound = errors.New("saved query not found")
+
+/**
+ * SavedQuery : A named query of a context, remembering the resources it
+ * matched when last run so that its new matches can be told apart
+ */
+
+type SavedQuery struct {
+	ID           string
+	ContextID    string
+	Name         string
+	Value        string
+	Filters      map[protocol.QueryFacetField][]string
+	MatchIDs     []string
+	MatchesTotal uint64
+	LastRunAt    time.Time
+}
+
+func (savedQuery *SavedQuery) MarshalMap() map[string]interface{} {
+	filters := map[string]interface{}{}
+
+	for field, values := range savedQuery.Filters {
+		filters[field.String()] = values
+	}
+
+	return map[string]interface{}{
+		"contextId":    savedQuery.ContextID,
+		"name":         savedQuery.Name,
+		"value":        savedQuery.Value,
+		"filters":      filters,
+		"matchIds":     savedQuery.MatchIDs,
+		"matchesTotal": int64(savedQuery.MatchesTotal),
+		"lastRunAt":    savedQuery.LastRunAt,
+	}
+}
+
+func (savedQuery *SavedQuery) MarshalProtocol() *protocol.SavedQuery {
+	savedQueryProto := &protocol.SavedQuery{
+		Id:           savedQuery.ID,
+		ContextId:    savedQuery.ContextID,
+		Name:         savedQuery.Name,
+		Value:        savedQuery.Value,
+		Filters:      make([]*protocol.QueryFilter, 0, len(savedQuery.Filters)),
+		MatchesTotal: int64(savedQuery.MatchesTotal),
+	}
+
+	for field, values := range savedQuery.Filters {
+		savedQueryProto.Filters = append(savedQueryProto.Filters, &protocol.QueryFilter{
+			Field:  field,
+			Values: values,
+		})
+	}
+
+	sort.Slice(savedQueryProto.Filters, func(i, j int) bool {
+		return savedQueryProto.Filters[i].Field < savedQueryProto.Filters[j].Field
+	})
+
+	if !savedQuery.LastRunAt.IsZero() {
+		savedQueryProto.LastRunAt = savedQuery.LastRunAt.UnixMilli()
+	}
+
+	return savedQueryProto
+}
+
+func (savedQuery *SavedQuery) UnmarshalDBDocument(document *clover.Document) error {
+	if document == nil {
+		return fmt.Errorf("cannot unmarshal nil document to saved query")
+	}
+
+	savedQuery.ID = document.ObjectId()
+	savedQuery.Name, _ = document.Get("name").(string)
+	savedQuery.Value, _ = document.Get("value").(string)
+	savedQuery.Filters = map[protocol.QueryFacetField][]string{}
+	savedQuery.MatchIDs = unmarshalStrings(document.Get("matchIds"))
+
+	if filters, isMap := document.Get("filters").(map[string]interface{}); isMap {
+		for name, values := range filters {
+			if field, isFacetField := protocol.QueryFacetField_value[name]; isFacetField {
+				savedQuery.Filters[protocol.QueryFacetField(field)] = unmarshalStrings(values)
+			}
+		}
+	}
+
+	if matchesTotal, isInt64 := document.Get("matchesTotal").(int64); isInt64 {
+		savedQuery.MatchesTotal = uint64(matchesTotal)
+	}
+
+	if lastRunAt, isTime := document.Get("lastRunAt").(time.Time); isTime {
+		savedQuery.LastRunAt = lastRunAt
+	}
+
+	return nil
+}
+
+func unmarshalStrings(value interface{}) (values []string) {
+	values = make([]string, 0)
+
+	items, isSlice := value.([]interface{})
+	if !isSlice {
+		return
+	}
+
+	for _, item := range items {
+		if itemString, isString := item.(string); isString {
+			values = append(values, itemString)
+		}
+	}
+
+	return
+}
+
+// SaveQuery stores a named query, replacing the one of the same name, and
+// runs it so that only the resources matching it later count as new
+func (context *Context) SaveQuery(name string, value string, filters map[protocol.QueryFacetField][]string) (savedQuery *SavedQuery, err error) {
+	context.savedQueriesMutex.Lock()
+	defer context.savedQueriesMutex.Unlock()
+
+	if savedQuery, err = context.findSavedQuery(clover.Field("name").Eq(name)); errors.Is(err, errSavedQueryNotFound) {
+		savedQuery, err = &SavedQuery{
+			ContextID: context.ID,
+			Name:      name,
+		}, nil
+	} else if err != nil {
+		return
+	}
+
+	savedQuery.Value = value
+	savedQuery.Filters = filters
+	savedQuery.MatchIDs = nil
+
+	_, _, err = context.runSavedQuery(savedQuery)
+	return
+}
+
+func (context *Context) GetSavedQueries() (savedQueries []*SavedQuery, err error) {
+	var documents []*clover.Document
+
+	if documents, err = context.engine.database.Query(ColSavedQueries).Where(
+		clover.Field("contextId").Eq(context.ID),
+	).Sort(clover.SortOption{Field: "name"}).FindAll(); err != nil {
+		return
+	}
+
+	savedQueries = make([]*SavedQuery, 0, len(documents))
+
+	for _, document := range documents {
+		savedQuery := &SavedQuery{
+			ContextID: context.ID,
+		}
+
+		if err = savedQuery.UnmarshalDBDocument(document); err != nil {
+			return nil, err
+		}
+
+		savedQueries = append(savedQueries, savedQuery)
+	}
+
+	return
+}
+
+func (context *Context) FindSavedQuery(savedQueryIDOrName string) (savedQuery *SavedQuery, err error) {
+	var document *clover.Document
+
+	if document, err = context.engine.database.Query(ColSavedQueries).FindById(savedQueryIDOrName); err != nil {
+		return
+	}
+
+	if document != nil && document.Get("contextId") == context.ID {
+		savedQuery = &SavedQuery{
+			ContextID: context.ID,
+		}
+
+		err = savedQuery.UnmarshalDBDocument(document)
+		return
+	}
+
+	if savedQuery, err = context.findSavedQuery(clover.Field("name").Eq(savedQueryIDOrName)); errors.Is(err, errSavedQueryNotFound) {
+		err = fmt.Errorf("%w: '%s'", errSavedQueryNotFound, savedQueryIDOrName)
+	}
+
+	return
+}
+
+func (context *Context) findSavedQuery(criteria *clover.Criteria) (savedQuery *SavedQuery, err error) {
+	var document *clover.Document
+
+	if document, err = context.engine.database.Query(ColSavedQueries).Where(
+		clover.Field("contextId").Eq(context.ID).And(criteria),
+	).FindFirst(); err != nil {
+		return
+	}
+
+	if document == nil {
+		return nil, errSavedQueryNotFound
+	}
+
+	savedQuery = &SavedQuery{
+		ContextID: context.ID,
+	}
+
+	err = savedQuery.UnmarshalDBDocument(document)
+	return
+}
+
+// RunSavedQuery searches a saved query, and tells the resources it matches
+// apart from those it matched when last run
+func (context *Context) RunSavedQuery(savedQueryIDOrName string) (savedQuery *SavedQuery, result *SearchResult, newResourceIDs []string, err error) {
+	context.savedQueriesMutex.Lock()
+	defer context.savedQueriesMutex.Unlock()
+
+	if savedQuery, err = context.FindSavedQuery(savedQueryIDOrName); err != nil {
+		return
+	}
+
+	result, newResourceIDs, err = context.runSavedQuery(savedQuery)
+	return
+}
+
+// runSavedQuery searches a saved query and stores its matches; the caller
+// holds the context's savedQueriesMutex
+func (context *Context) runSavedQuery(savedQuery *SavedQuery) (result *SearchResult, newResourceIDs []string, err error) {
+	var searchQuery query.Query
+
+	if searchQuery, err = UnmarshalSearchQuery(&protocol.QueryRequest{
+		Value: savedQuery.Value,
+	}); err != nil {
+		return
+	}
+
+	if result, err = context.Search(searchQuery, &SearchOptions{
+		Limit:   maxSavedQueryMatches,
+		Filters: savedQuery.Filters,
+	}); err != nil {
+		return
+	}
+
+	previousMatches := map[string]bool{}
+
+	for _, resourceID := range savedQuery.MatchIDs {
+		previousMatches[resourceID] = true
+	}
+
+	savedQuery.MatchIDs = make([]string, 0, len(result.Hits))
+	newResourceIDs = make([]string, 0)
+
+	for _, hit := range result.Hits {
+		resourceID := *hit.Resource.ID()
+
+		savedQuery.MatchIDs = append(savedQuery.MatchIDs, resourceID)
+
+		if !previousMatches[resourceID] {
+			newResourceIDs = append(newResourceIDs, resourceID)
+		}
+	}
+
+	savedQuery.MatchesTotal = result.Total
+	savedQuery.LastRunAt = time.Now()
+
+	if savedQuery.ID == "" {
+		document := clover.NewDocument()
+		document.SetAll(savedQuery.MarshalMap())
+
+		savedQuery.ID, err = context.engine.database.InsertOne(ColSavedQueries, document)
+		return
+	}
+
+	err = context.engine.database.Query(ColSavedQueries).UpdateById(savedQuery.ID, savedQuery.MarshalMap())
+	return
+}
+
+// evaluateSavedQueries runs again the saved queries of a context once it has
+// been indexed, notifying of their new matches
+func (engine *Engine) evaluateSavedQueries(context *Context) {
+	context.savedQueriesMutex.Lock()
+	defer context.savedQueriesMutex.Unlock()
+
+	savedQueries, err := context.GetSavedQueries()
+	if err != nil {
+		engine.events.PublishError(context.ID, err)
+		return
+	}
+
+	for _, savedQuery := range savedQueries {
+		var newResourceIDs []string
+
+		if _, newResourceIDs, err = context.runSavedQuery(savedQuery); err != nil {
+			engine.events.PublishError(context.ID, err)
+			continue
+		}
+
+		if len(newResourceIDs) > 0 {
+			engine.events.PublishSavedQueryMatched(savedQuery, newResourceIDs)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/necessitates/clover"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"risp/protocol"
+)
+
+const defaultSearchHistoryLimit = 100
+
+var errSearchNotFound = errors.New("search not found")
+
+/**
+ * SearchHistoryEntry : A query run over one or more contexts, along with how
+ * many hits it found and which of them were opened
+ */
+
+type SearchHistoryEntry struct {
+	ID                string
+	ContextIDs        []string
+	Value             string
+	HitsTotal         uint64
+	SearchedAt        time.Time
+	OpenedResourceIDs []string
+}
+
+func (entry *SearchHistoryEntry) MarshalMap() map[string]interface{} {
+	return map[string]interface{}{
+		"contextIds":        entry.ContextIDs,
+		"value":             entry.Value,
+		"hitsTotal":         int64(entry.HitsTotal),
+		"searchedAt":        entry.SearchedAt,
+		"openedResourceIds": entry.OpenedResourceIDs,
+	}
+}
+
+func (entry *SearchHistoryEntry) MarshalProtocol() *protocol.SearchHistoryEntry {
+	return &protocol.SearchHistoryEntry{
+		Id:                entry.ID,
+		ContextIds:        entry.ContextIDs,
+		Value:             entry.Value,
+		HitsTotal:         int64(entry.HitsTotal),
+		SearchedAt:        entry.SearchedAt.UnixMilli(),
+		OpenedResourceIds: entry.OpenedResourceIDs,
+	}
+}
+
+func (entry *SearchHistoryEntry) UnmarshalDBDocument(document *clover.Document) error {
+	if document == nil {
+		return fmt.Errorf("cannot unmarshal nil document to search history entry")
+	}
+
+	entry.ID = document.ObjectId()
+	entry.ContextIDs = unmarshalStrings(document.Get("contextIds"))
+	entry.Value, _ = document.Get("value").(string)
+	entry.OpenedResourceIDs = unmarshalStrings(document.Get("openedResourceIds"))
+
+	if hitsTotal, isInt64 := document.Get("hitsTotal").(int64); isInt64 {
+		entry.HitsTotal = uint64(hitsTotal)
+	}
+
+	if searchedAt, isTime := document.Get("searchedAt").(time.Time); isTime {
+		entry.SearchedAt = searchedAt
+	}
+
+	return nil
+}
+
+// recordSearch adds a query to the search history of the contexts it ran over
+func (engine *Engine) recordSearch(contexts []*Context, request *protocol.QueryRequest, hitsTotal uint64) (entry *SearchHistoryEntry, err error) {
+	entry = &SearchHistoryEntry{
+		ContextIDs:        make([]string, 0, len(contexts)),
+		Value:             request.Value,
+		HitsTotal:         hitsTotal,
+		SearchedAt:        time.Now(),
+		OpenedResourceIDs: make([]string, 0),
+	}
+
+	for _, context := range contexts {
+		entry.ContextIDs = append(entry.ContextIDs, context.ID)
+	}
+
+	if request.Query != nil {
+		var value []byte
+
+		if value, err = protojson.Marshal(request.Query); err != nil {
+			return
+		}
+
+		entry.Value = string(value)
+	}
+
+	document := clover.NewDocument()
+	document.SetAll(entry.MarshalMap())
+
+	entry.ID, err = engine.database.InsertOne(ColSearchHistory, document)
+	return
+}
+
+// openSearchResult records that a resource found by a search was opened
+func (engine *Engine) openSearchResult(searchID string, resourceID string) (err error) {
+	var document *clover.Document
+
+	if document, err = engine.database.Query(ColSearchHistory).FindById(searchID); err != nil {
+		return
+	}
+
+	if document == nil {
+		return fmt.Errorf("%w: '%s'", errSearchNotFound, searchID)
+	}
+
+	entry := &SearchHistoryEntry{}
+
+	if err = entry.UnmarshalDBDocument(document); err != nil {
+		return
+	}
+
+	for _, openedResourceID := range entry.OpenedResourceIDs {
+		if openedResourceID == resourceID {
+			return
+		}
+	}
+
+	return engine.database.Query(ColSearchHistory).UpdateById(searchID, map[string]interface{}{
+		"openedResourceIds": append(entry.OpenedResourceIDs, resourceID),
+	})
+}
+
+// GetSearchHistory lists the searches of the context, the most recent first;
+// zeroHits lists only those that found nothing, showing gaps in coverage
+func (context *Context) GetSearchHistory(limit, offset int, zeroHits bool) (entries []*SearchHistoryEntry, total int, err error) {
+	var documents []*clover.Document
+
+	criteria := clover.Field("contextIds").Contains(context.ID)
+
+	if zeroHits {
+		criteria = criteria.And(clover.Field("hitsTotal").Eq(0))
+	}
+
+	whereQuery := context.engine.database.Query(ColSearchHistory).Where(criteria)
+
+	if total, err = whereQuery.Count(); err != nil {
+		return
+	}
+
+	if documents, err = whereQuery.Sort(clover.SortOption{
+		Field:     "searchedAt",
+		Direction: -1,
+	}).Skip(offset).Limit(limit).FindAll(); err != nil {
+		return
+	}
+
+	entries = make([]*SearchHistoryEntry, 0, len(documents))
+
+	for _, document := range documents {
+		entry := &SearchHistoryEntry{}
+
+		if err = entry.UnmarshalDBDocument(document); err != nil {
+			return nil, 0, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return
+}
+
+// ClearSearchHistory forgets the searches of the context; searches run over
+// other contexts too are kept in theirs
+func (context *Context) ClearSearchHistory() (cleared int, err error) {
+	var documents []*clover.Document
+
+	historyQuery := context.engine.database.Query(ColSearchHistory)
+
+	if documents, err = historyQuery.Where(
+		clover.Field("contextIds").Contains(context.ID),
+	).FindAll(); err != nil {
+		return
+	}
+
+	for _, document := range documents {
+		contextIDs := make([]string, 0)
+
+		for _, contextID := range unmarshalStrings(document.Get("contextIds")) {
+			if contextID != context.ID {
+				contextIDs = append(contextIDs, contextID)
+			}
+		}
+
+		if len(contextIDs) > 0 {
+			err = historyQuery.UpdateById(document.ObjectId(), map[string]interface{}{
+				"contextIds": contextIDs,
+			})
+		} else {
+			err = historyQuery.DeleteById(document.ObjectId())
+		}
+
+		if err != nil {
+			return
+		}
+
+		cleared++
+	}
+
+	return
+}
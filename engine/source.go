@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/blevesearch/bleve/v2"
 	"github.com/necessitates/clover"
@@ -11,20 +12,118 @@ import (
 	"risp/protocol"
 )
 
+type SourceStats struct {
+	ResourcesTotal int64
+	BytesIndexed   int64
+	LastIndexedAt  time.Time
+	LastError      string
+}
+
+func (sourceStats *SourceStats) MarshalMap() map[string]interface{} {
+	return map[string]interface{}{
+		"resourcesTotal": sourceStats.ResourcesTotal,
+		"bytesIndexed":   sourceStats.BytesIndexed,
+		"lastIndexedAt":  sourceStats.LastIndexedAt,
+		"lastError":      sourceStats.LastError,
+	}
+}
+
+func (sourceStats *SourceStats) MarshalProtocol(source *protocol.Source) {
+	if source == nil {
+		return
+	}
+
+	source.ResourcesTotal = sourceStats.ResourcesTotal
+	source.BytesIndexed = sourceStats.BytesIndexed
+
+	if !sourceStats.LastIndexedAt.IsZero() {
+		source.LastIndexedAt = sourceStats.LastIndexedAt.UnixMilli()
+	}
+
+	if sourceStats.LastError != "" {
+		lastError := sourceStats.LastError
+		source.LastError = &lastError
+	}
+}
+
+func (sourceStats *SourceStats) UnmarshalDBDocument(document *clover.Document) (err error) {
+	if document == nil {
+		return
+	}
+
+	if value, isInt64 := document.Get("stats.resourcesTotal").(int64); isInt64 {
+		sourceStats.ResourcesTotal = value
+	}
+
+	if value, isInt64 := document.Get("stats.bytesIndexed").(int64); isInt64 {
+		sourceStats.BytesIndexed = value
+	}
+
+	if value, isTime := document.Get("stats.lastIndexedAt").(time.Time); isTime {
+		sourceStats.LastIndexedAt = value
+	}
+
+	if value, isString := document.Get("stats.lastError").(string); isString {
+		sourceStats.LastError = value
+	}
+
+	return
+}
+
 type Source struct {
 	ContextID    string
 	ID           string
 	CanonicalURI string
 	AdapterType  AdapterType
 	AdapterData  AdapterData
+	Stats        SourceStats
+	Weight       float64 // 0 weighs as defaultWeight
+}
+
+func (source *Source) weight() float64 {
+	if source.Weight == 0 {
+		return defaultWeight
+	}
+
+	return source.Weight
 }
 
-func (source *Source) Adapter(database *clover.DB, index bleve.Index) Adapter {
+func canonicalizeSourceURI(uri string) (canonicalURI string, adapterType AdapterType, err error) {
+	var parsedURI *url.URL
+
+	if parsedURI, err = url.Parse(uri); err != nil {
+		return "", "", fmt.Errorf("invalid URI '%s': %+v", uri, err)
+	}
+
+	switch parsedURI.Scheme {
+	case "file":
+		adapterType = AdapterTypeFS
+		canonicalURI = (&url.URL{
+			Scheme: parsedURI.Scheme,
+			Host:   parsedURI.Host,
+			User:   parsedURI.User,
+			Path:   parsedURI.Path,
+		}).String()
+	case "http", "https":
+		adapterType = AdapterTypeWeb
+		canonicalURI = (&url.URL{
+			Scheme: parsedURI.Scheme,
+			Host:   parsedURI.Host,
+			User:   parsedURI.User,
+		}).String()
+	default:
+		err = fmt.Errorf("invalid URI scheme '%s', expected 'file' or 'http(s)'", parsedURI.Scheme)
+	}
+
+	return
+}
+
+func (source *Source) Adapter(database *clover.DB, index bleve.Index, events *EventBus) Adapter {
 	switch source.AdapterType {
 	case AdapterTypeFS:
-		return NewAdapterFS(source, database, index)
+		return NewAdapterFS(source, database, index, events)
 	case AdapterTypeWeb:
-		return NewAdapterWeb(source, database, index)
+		return NewAdapterWeb(source, database, index, events)
 	}
 
 	return nil
@@ -65,6 +164,10 @@ func (source *Source) MarshalMap() (value map[string]interface{}) {
 		"urn":          source.MarshalURN(),
 	}
 
+	if source.Weight != 0 {
+		value["weight"] = source.Weight
+	}
+
 	if source.AdapterData != nil {
 		value["adapterData"] = source.AdapterData.MarshalMap()
 	}
@@ -78,6 +181,7 @@ func (source *Source) MarshalProtocol() (sourceProto *protocol.Source) {
 		Id:           source.ID,
 		CanonicalUri: source.CanonicalURI,
 		Urn:          source.MarshalURN(),
+		Weight:       source.weight(),
 	}
 
 	switch source.AdapterType {
@@ -91,6 +195,8 @@ func (source *Source) MarshalProtocol() (sourceProto *protocol.Source) {
 		source.AdapterData.MarshalProtocol(sourceProto)
 	}
 
+	source.Stats.MarshalProtocol(sourceProto)
+
 	return
 }
 
@@ -107,7 +213,7 @@ func (source *Source) UnmarshalMap(value map[string]interface{}) error {
 		if value["adapterType"] != nil && value["adapterType"] != "" {
 			source.AdapterType = AdapterType(value["adapterType"].(string))
 
-			if source.Adapter(nil, nil) == nil {
+			if source.Adapter(nil, nil, nil) == nil {
 				return fmt.Errorf("invalid adapter: '%s'", source.AdapterType)
 			}
 		} else {
@@ -127,7 +233,7 @@ func (source *Source) UnmarshalMap(value map[string]interface{}) error {
 		}
 	}
 
-	return source.Adapter(nil, nil).UnmarshalMap(value)
+	return source.Adapter(nil, nil, nil).UnmarshalMap(value)
 }
 
 func (source *Source) UnmarshalDBDocument(document *clover.Document) error {
@@ -143,7 +249,7 @@ func (source *Source) UnmarshalDBDocument(document *clover.Document) error {
 		if document.Get("adapterType") != nil && document.Get("adapterType") != "" {
 			source.AdapterType = AdapterType(document.Get("adapterType").(string))
 
-			if source.Adapter(nil, nil) == nil {
+			if source.Adapter(nil, nil, nil) == nil {
 				return fmt.Errorf("invalid adapter: '%s'", source.AdapterType)
 			}
 		} else {
@@ -163,5 +269,13 @@ func (source *Source) UnmarshalDBDocument(document *clover.Document) error {
 		}
 	}
 
-	return source.Adapter(nil, nil).UnmarshalDBDocument(document)
+	if err := source.Stats.UnmarshalDBDocument(document); err != nil {
+		return err
+	}
+
+	if weight, isWeight := unmarshalWeight(document.Get("weight")); isWeight {
+		source.Weight = weight
+	}
+
+	return source.Adapter(nil, nil, nil).UnmarshalDBDocument(document)
 }
@@ -0,0 +1,210 @@
+package engine
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/blevesearch/bleve/v2/search/query"
+
+	"risp/protocol"
+)
+
+const (
+	// spellcheckMaxHits is the most hits a query may find and still have its
+	// spelling corrected
+	spellcheckMaxHits = 2
+	// spellcheckField is the field query strings search by default
+	spellcheckField = "_all"
+)
+
+// CorrectQueryString proposes the query string with its unknown terms
+// replaced by the closest known ones, or "" when there is nothing better to
+// propose than the query itself; the terms of the correction must find more
+// hits than the query did, under the same search options
+func CorrectQueryString(contexts []*Context, value string, total uint64, options *SearchOptions) (correction string, err error) {
+	var (
+		words       = strings.Fields(value)
+		isCorrected bool
+	)
+
+	for i, word := range words {
+		var corrected, operator string
+
+		if strings.HasPrefix(word, "+") || strings.HasPrefix(word, "-") {
+			operator = word[:1]
+		}
+
+		if !isCorrectableWord(word[len(operator):]) {
+			continue
+		}
+
+		if corrected, err = correctTerm(contexts, strings.ToLower(word[len(operator):])); err != nil {
+			return "", err
+		}
+
+		if corrected != "" {
+			words[i] = operator + corrected
+			isCorrected = true
+		}
+	}
+
+	if !isCorrected {
+		return "", nil
+	}
+
+	correction = strings.Join(words, " ")
+
+	var (
+		correctionQuery  query.Query
+		correctionResult *SearchResult
+		countOptions     = SearchOptions{}
+	)
+
+	if options != nil {
+		countOptions = *options
+	}
+
+	// the hits are only counted, the ranking being left out as it changes no count
+	countOptions.Limit, countOptions.Offset, countOptions.SortBy = 1, 0, []string{"_id"}
+	countOptions.Facets, countOptions.HighlightStyle, countOptions.Explain = nil, "", false
+
+	if correctionQuery, err = UnmarshalSearchQuery(&protocol.QueryRequest{Value: correction}); err != nil {
+		return "", err
+	}
+
+	if correctionResult, err = SearchContexts(contexts, correctionQuery, &countOptions); err != nil {
+		return "", err
+	}
+
+	if correctionResult.Total <= total {
+		return "", nil
+	}
+
+	return
+}
+
+// isCorrectableWord leaves out the words of query string syntax: fields,
+// operators, phrases, wildcards, fuzziness, boosts and regexps
+func isCorrectableWord(word string) bool {
+	if utf8.RuneCountInString(word) < 3 {
+		return false
+	}
+
+	for _, char := range word {
+		if !unicode.IsLetter(char) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// correctTerm finds the known term closest to an unknown one, among the
+// terms sharing its first letter: a typo in the first letter is left alone,
+// so that a single prefix of the dictionary is walked rather than all of it.
+// The most frequent, then the first in alphabetical order, wins a tie
+func correctTerm(contexts []*Context, term string) (corrected string, err error) {
+	var (
+		maxDistance  = maxEditDistance(term)
+		bestDistance = maxDistance + 1
+		bestCount    uint64
+		firstLetter  = string([]rune(term)[:1])
+		termLength   = utf8.RuneCountInString(term)
+		counts       = map[string]uint64{}
+	)
+
+	for _, context := range contexts {
+		fieldDict, dictErr := context.index.FieldDictPrefix(spellcheckField, []byte(firstLetter))
+		if dictErr != nil {
+			return "", dictErr
+		}
+
+		for {
+			entry, nextErr := fieldDict.Next()
+			if err = nextErr; err != nil || entry == nil {
+				break
+			}
+
+			// terms differing in length by more than the edits allowed are too far
+			if lengthDifference := utf8.RuneCountInString(entry.Term) - termLength; lengthDifference > maxDistance || -lengthDifference > maxDistance {
+				continue
+			}
+
+			counts[entry.Term] += entry.Count
+		}
+
+		if closeErr := fieldDict.Close(); err == nil {
+			err = closeErr
+		}
+
+		if err != nil {
+			return
+		}
+	}
+
+	if counts[term] > 0 {
+		return "", nil
+	}
+
+	for candidate, count := range counts {
+		distance := editDistance(term, candidate)
+
+		if distance < bestDistance ||
+			(distance == bestDistance && count > bestCount) ||
+			(distance == bestDistance && count == bestCount && candidate < corrected) {
+			corrected, bestDistance, bestCount = candidate, distance, count
+		}
+	}
+
+	return
+}
+
+// maxEditDistance allows one edit to short terms and two to longer ones
+func maxEditDistance(term string) int {
+	if utf8.RuneCountInString(term) < 5 {
+		return 1
+	}
+
+	return 2
+}
+
+// editDistance is the Levenshtein distance of two terms
+func editDistance(a, b string) int {
+	aRunes, bRunes := []rune(a), []rune(b)
+	previous := make([]int, len(bRunes)+1)
+	current := make([]int, len(bRunes)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(aRunes); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(bRunes); j++ {
+			cost := 1
+			if aRunes[i-1] == bRunes[j-1] {
+				cost = 0
+			}
+
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(bRunes)]
+}
+
+func minInt(values ...int) (min int) {
+	min = values[0]
+
+	for _, value := range values[1:] {
+		if value < min {
+			min = value
+		}
+	}
+
+	return
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"testing"
+
+	"risp/protocol"
+)
+
+func TestCorrectTerm(t *testing.T) {
+	engine, defaultContext := newTestEngine(t)
+
+	indexTestSource(t, engine, defaultContext, newTestSourceDirOf(t, map[string]string{
+		"animals.txt": "elephant elephant giraffe",
+		"cards.txt":   "card cart",
+	}))
+
+	for _, test := range []struct {
+		term, corrected string
+	}{
+		{"elephnat", "elephant"},
+		{"girafe", "giraffe"},
+		{"elephant", ""}, // known already
+		{"lephant", ""},  // typo in the first letter, left alone
+		{"carx", "card"}, // tie of "card" and "cart", broken alphabetically
+		{"zebra", ""},
+	} {
+		corrected, err := correctTerm([]*Context{defaultContext}, test.term)
+		if err != nil {
+			t.Fatalf("correcting %q: %+v", test.term, err)
+		}
+
+		if corrected != test.corrected {
+			t.Errorf("corrected %q into %q, want %q", test.term, corrected, test.corrected)
+		}
+	}
+}
+
+// TestCorrectQueryStringFilters proposes no correction finding nothing under
+// the filters of the query, though finding hits without them
+func TestCorrectQueryStringFilters(t *testing.T) {
+	engine, defaultContext := newTestEngine(t)
+
+	indexTestSource(t, engine, defaultContext, newTestSourceDirOf(t, map[string]string{
+		"animals.txt": "elephant",
+	}))
+	_, otherSource := indexTestSource(t, engine, defaultContext, newTestSourceDirOf(t, map[string]string{
+		"other.txt": "nothing alike",
+	}))
+
+	for _, test := range []struct {
+		options    *SearchOptions
+		correction string
+	}{
+		{&SearchOptions{}, "elephant"},
+		{&SearchOptions{Filters: map[protocol.QueryFacetField][]string{
+			protocol.QueryFacetField_SOURCE: {otherSource.ID},
+		}}, ""},
+	} {
+		correction, err := CorrectQueryString([]*Context{defaultContext}, "elephnat", 0, test.options)
+		if err != nil {
+			t.Fatalf("correcting under %+v: %+v", test.options.Filters, err)
+		}
+
+		if correction != test.correction {
+			t.Errorf("correction under %+v is %q, want %q", test.options.Filters, correction, test.correction)
+		}
+	}
+}
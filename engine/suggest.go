@@ -0,0 +1,290 @@
+package engine
+
+import (
+	"container/heap"
+	"sort"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+
+	"risp/protocol"
+)
+
+const (
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 100
+)
+
+// suggestTermFields hold the terms completing the last word typed
+var suggestTermFields = []string{RecordTitleField, RecordContentField}
+
+/**
+ * Suggestion : A completion of the query text typed so far
+ */
+
+type Suggestion struct {
+	Value string
+	Kind  protocol.QuerySuggestionKind
+	Count uint64
+}
+
+func (suggestion *Suggestion) MarshalProtocol() *protocol.QuerySuggestion {
+	return &protocol.QuerySuggestion{
+		Value: suggestion.Value,
+		Kind:  suggestion.Kind,
+		Count: int64(suggestion.Count),
+	}
+}
+
+type suggestions map[string]*Suggestion
+
+/**
+ * suggestTerms : The best terms read from a dictionary so far, as a heap
+ * holding the worst of them on top, so that a whole dictionary is ranked
+ * while keeping only as many terms as suggested
+ */
+
+type suggestTerm struct {
+	term  string
+	count uint64
+}
+
+type suggestTerms []suggestTerm
+
+// isBetterThan ranks the more frequent terms first, then the shorter ones
+func (term suggestTerm) isBetterThan(other suggestTerm) bool {
+	switch true {
+	case term.count != other.count:
+		return term.count > other.count
+	case len(term.term) != len(other.term):
+		return len(term.term) < len(other.term)
+	}
+
+	return term.term < other.term
+}
+
+func (terms suggestTerms) Len() int           { return len(terms) }
+func (terms suggestTerms) Less(i, j int) bool { return terms[j].isBetterThan(terms[i]) }
+func (terms suggestTerms) Swap(i, j int)      { terms[i], terms[j] = terms[j], terms[i] }
+
+func (terms *suggestTerms) Push(term interface{}) {
+	*terms = append(*terms, term.(suggestTerm))
+}
+
+func (terms *suggestTerms) Pop() interface{} {
+	last := (*terms)[len(*terms)-1]
+	*terms = (*terms)[:len(*terms)-1]
+	return last
+}
+
+// keep adds a term to the best ones, unless as many better ones are kept
+func (terms *suggestTerms) keep(term suggestTerm, size int) {
+	if terms.Len() < size {
+		heap.Push(terms, term)
+	} else if term.isBetterThan((*terms)[0]) {
+		(*terms)[0] = term
+		heap.Fix(terms, 0)
+	}
+}
+
+// add keeps one suggestion per case-insensitive value, of the kind it was
+// first found as and the highest of the counts it was found with
+func (suggestions suggestions) add(value string, kind protocol.QuerySuggestionKind, count uint64) {
+	key := strings.ToLower(value)
+
+	if suggestion, hasSuggestion := suggestions[key]; hasSuggestion {
+		if count > suggestion.Count {
+			suggestion.Count = count
+		}
+
+		return
+	}
+
+	suggestions[key] = &Suggestion{
+		Value: value,
+		Kind:  kind,
+		Count: count,
+	}
+}
+
+// Suggest completes the query text typed so far from the terms, titles,
+// filenames and paths of the context's resources. Suggestions starting with
+// the whole text rank first, then the more frequent, then the shorter ones
+func (context *Context) Suggest(value string, limit int) (result []*Suggestion, err error) {
+	var (
+		candidates = suggestions{}
+		text       = strings.TrimLeft(value, " ")
+		lead       string
+		prefix     = text
+	)
+
+	if limit < 1 {
+		limit = defaultSuggestLimit
+	} else if limit > maxSuggestLimit {
+		limit = maxSuggestLimit
+	}
+
+	if lastSpace := strings.LastIndex(text, " "); lastSpace >= 0 {
+		lead, prefix = text[:lastSpace+1], text[lastSpace+1:]
+	}
+
+	if len(prefix) < 1 {
+		return []*Suggestion{}, nil
+	}
+
+	for _, field := range suggestTermFields {
+		if err = context.suggestFromDictionary(candidates, field, prefix, func(term string) string {
+			return lead + term
+		}, protocol.QuerySuggestionKind_TERM, limit); err != nil {
+			return
+		}
+	}
+
+	if err = context.suggestFromDictionary(candidates, RecordFilenameField, text, nil, protocol.QuerySuggestionKind_NAME, limit); err != nil {
+		return
+	}
+
+	if err = context.suggestFromDictionary(candidates, RecordPathField, text, nil, protocol.QuerySuggestionKind_PATH, limit); err != nil {
+		return
+	}
+
+	if err = context.suggestTitles(candidates, lead, prefix, limit); err != nil {
+		return
+	}
+
+	result = make([]*Suggestion, 0, len(candidates))
+	lowerText := strings.ToLower(text)
+
+	for _, suggestion := range candidates {
+		if suggestion.Value != text {
+			result = append(result, suggestion)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		iIsPrefix := strings.HasPrefix(strings.ToLower(result[i].Value), lowerText)
+		jIsPrefix := strings.HasPrefix(strings.ToLower(result[j].Value), lowerText)
+
+		switch true {
+		case iIsPrefix != jIsPrefix:
+			return iIsPrefix
+		case result[i].Count != result[j].Count:
+			return result[i].Count > result[j].Count
+		case len(result[i].Value) != len(result[j].Value):
+			return len(result[i].Value) < len(result[j].Value)
+		}
+
+		return result[i].Value < result[j].Value
+	})
+
+	if len(result) > limit {
+		result = result[:limit]
+	}
+
+	return
+}
+
+// suggestFromDictionary suggests the best terms of a field starting with the
+// prefix, as typed and in lower case, since only keyword fields keep case;
+// the terms are ranked as the dictionary is read, all of it, so that the
+// frequent terms sorting last alphabetically are suggested as well
+func (context *Context) suggestFromDictionary(
+	candidates suggestions,
+	field string,
+	prefix string,
+	complete func(term string) string,
+	kind protocol.QuerySuggestionKind,
+	limit int,
+) (err error) {
+	prefixes := []string{prefix}
+
+	if lowerPrefix := strings.ToLower(prefix); lowerPrefix != prefix {
+		prefixes = append(prefixes, lowerPrefix)
+	}
+
+	for _, termPrefix := range prefixes {
+		bestTerms := &suggestTerms{}
+
+		fieldDict, dictErr := context.index.FieldDictPrefix(field, []byte(termPrefix))
+		if dictErr != nil {
+			return dictErr
+		}
+
+		for {
+			entry, nextErr := fieldDict.Next()
+			if err = nextErr; err != nil || entry == nil {
+				break
+			}
+
+			if entry.Count < 1 {
+				continue
+			}
+
+			// one more than suggested, as the text typed is no suggestion of its own
+			bestTerms.keep(suggestTerm{entry.Term, entry.Count}, limit+1)
+		}
+
+		if closeErr := fieldDict.Close(); err == nil {
+			err = closeErr
+		}
+
+		if err != nil {
+			return
+		}
+
+		for _, term := range *bestTerms {
+			value := term.term
+			if complete != nil {
+				value = complete(term.term)
+			}
+
+			candidates.add(value, kind, term.count)
+		}
+	}
+
+	return
+}
+
+// suggestTitles suggests the titles holding the words typed so far, the
+// last one as a prefix
+func (context *Context) suggestTitles(candidates suggestions, lead string, prefix string, limit int) (err error) {
+	var searchResult *bleve.SearchResult
+
+	prefixQuery := bleve.NewPrefixQuery(strings.ToLower(prefix))
+	prefixQuery.SetField(RecordTitleField)
+
+	titleQuery := bleve.NewConjunctionQuery(
+		prefixQuery,
+		filterQuery(RecordTypeField, []string{string(RecordResource)}),
+	)
+
+	if strings.TrimSpace(lead) != "" {
+		leadQuery := bleve.NewMatchQuery(lead)
+		leadQuery.SetField(RecordTitleField)
+		leadQuery.SetOperator(query.MatchQueryOperatorAnd)
+
+		titleQuery.AddQuery(leadQuery)
+	}
+
+	searchRequest := bleve.NewSearchRequestOptions(titleQuery, limit*2, 0, false)
+	searchRequest.Fields = []string{RecordTitleField}
+
+	if searchResult, err = context.index.Search(searchRequest); err != nil {
+		return
+	}
+
+	titleCounts := map[string]uint64{}
+
+	for _, hit := range searchResult.Hits {
+		if title, isString := hit.Fields[RecordTitleField].(string); isString && title != "" {
+			titleCounts[title]++
+		}
+	}
+
+	for title, count := range titleCounts {
+		candidates.add(title, protocol.QuerySuggestionKind_TITLE, count)
+	}
+
+	return
+}
@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestSuggestRanksWholeDictionary suggests a frequent term sorting after more
+// terms of its prefix than a truncated dictionary would read
+func TestSuggestRanksWholeDictionary(t *testing.T) {
+	engine, defaultContext := newTestEngine(t)
+
+	rareTerms := make([]string, 0, 12000)
+	for i := 0; i < cap(rareTerms); i++ {
+		rareTerms = append(rareTerms, fmt.Sprintf("aa%05d", i))
+	}
+
+	indexTestSource(t, engine, defaultContext, newTestSourceDirOf(t, map[string]string{
+		"rare.txt":  strings.Join(rareTerms, " "),
+		"sky-1.txt": "azure",
+		"sky-2.txt": "azure",
+		"sky-3.txt": "azure",
+	}))
+
+	suggestions, err := defaultContext.Suggest("a", 1)
+	if err != nil {
+		t.Fatalf("suggesting: %+v", err)
+	}
+
+	if len(suggestions) != 1 || suggestions[0].Value != "azure" {
+		for _, suggestion := range suggestions {
+			t.Logf("suggested %+v", *suggestion)
+		}
+
+		t.Errorf("suggested %d terms, want azure alone", len(suggestions))
+	}
+}
@@ -0,0 +1,267 @@
+package engine
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/necessitates/clover"
+)
+
+// watchDebounce is how long a watched source must go without changes before
+// being reindexed, so that editors saving in bursts reindex it once
+const watchDebounce = 500 * time.Millisecond
+
+/**
+ * sourceWatcher : Watches the directories of an FS source, reindexing the
+ * paths changed once the changes settle; its reindexes never overlap
+ */
+
+type sourceWatcher struct {
+	engine       *Engine
+	context      *Context
+	source       *Source
+	path         string
+	fsWatcher    *fsnotify.Watcher
+	job          *Job            // the latest reindex, guarded by the engine's watchersMutex
+	changedPaths map[string]bool // relative to the source, since the latest reindex
+}
+
+// watchSource starts watching a directory source, unless it is already
+// watched; other sources are left alone
+func (engine *Engine) watchSource(context *Context, source *Source) (err error) {
+	adapterDataFS, isFS := source.AdapterData.(*AdapterDataFS)
+	if !isFS || !adapterDataFS.IsDir {
+		return
+	}
+
+	engine.watchersMutex.Lock()
+	defer engine.watchersMutex.Unlock()
+
+	if _, isWatched := engine.watchers[source.ID]; isWatched || engine.watchersClosed {
+		return
+	}
+
+	watcher := &sourceWatcher{
+		engine:       engine,
+		context:      context,
+		source:       source,
+		path:         adapterDataFS.Path,
+		changedPaths: map[string]bool{},
+	}
+
+	if watcher.fsWatcher, err = fsnotify.NewWatcher(); err != nil {
+		return
+	}
+
+	if err = watcher.watchDir(adapterDataFS.Path); err != nil {
+		watcher.fsWatcher.Close()
+		return
+	}
+
+	engine.watchers[source.ID] = watcher
+
+	go watcher.run()
+	return
+}
+
+// watchContextSources watches the directory sources of a loaded context
+// again; catchUpWatchedSources then reindexes them
+func (engine *Engine) watchContextSources(context *Context) (err error) {
+	return context.eachSource(clover.Field("adapterType").Eq(string(AdapterTypeFS)), func(source *Source) error {
+		if watchErr := engine.watchSource(context, source); watchErr != nil {
+			engine.events.PublishError(context.ID, watchErr)
+		}
+
+		return nil
+	})
+}
+
+// catchUpWatchedSources reindexes in the background, one source at a time,
+// the watched sources for the changes made while the engine was stopped; the
+// files left unchanged are skipped without being read
+func (engine *Engine) catchUpWatchedSources() {
+	engine.watchersMutex.Lock()
+
+	watchers := make([]*sourceWatcher, 0, len(engine.watchers))
+	for _, watcher := range engine.watchers {
+		watchers = append(watchers, watcher)
+	}
+
+	engine.watchersMutex.Unlock()
+
+	engine.jobsMutex.Lock()
+	defer engine.jobsMutex.Unlock()
+
+	// as with runJob, a stopping engine may be waiting for its jobs already
+	if engine.getIsStopping() {
+		return
+	}
+
+	engine.jobsGroup.Add(1)
+
+	go func() {
+		defer engine.jobsGroup.Done()
+
+		for _, watcher := range watchers {
+			watcher.catchUp()
+		}
+	}()
+}
+
+func (engine *Engine) unwatchSource(sourceID string) {
+	engine.watchersMutex.Lock()
+	defer engine.watchersMutex.Unlock()
+
+	if watcher, isWatched := engine.watchers[sourceID]; isWatched {
+		delete(engine.watchers, sourceID)
+		watcher.fsWatcher.Close()
+	}
+}
+
+func (engine *Engine) unwatchContext(contextID string) {
+	engine.watchersMutex.Lock()
+	defer engine.watchersMutex.Unlock()
+
+	for sourceID, watcher := range engine.watchers {
+		if watcher.context.ID == contextID {
+			delete(engine.watchers, sourceID)
+			watcher.fsWatcher.Close()
+		}
+	}
+}
+
+// closeWatchers stops all the watches, for good, as the engine stops
+func (engine *Engine) closeWatchers() {
+	engine.watchersMutex.Lock()
+	defer engine.watchersMutex.Unlock()
+
+	engine.watchersClosed = true
+
+	for sourceID, watcher := range engine.watchers {
+		delete(engine.watchers, sourceID)
+		watcher.fsWatcher.Close()
+	}
+}
+
+// watchDir watches a directory and its subdirectories; the subdirectories
+// which cannot be watched are reported but do not fail the watch
+func (watcher *sourceWatcher) watchDir(path string) error {
+	return filepath.WalkDir(path, func(subpath string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.IsDir() {
+			err = watcher.fsWatcher.Add(subpath)
+		}
+
+		if err != nil {
+			if subpath == path {
+				return err
+			}
+
+			watcher.engine.events.PublishError(watcher.context.ID, err)
+		}
+
+		return nil
+	})
+}
+
+func (watcher *sourceWatcher) run() {
+	var debounce <-chan time.Time
+
+	for {
+		select {
+		case event, isOpen := <-watcher.fsWatcher.Events:
+			if !isOpen {
+				return
+			}
+
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+
+			if event.Op&fsnotify.Create != 0 {
+				if stat, err := os.Stat(event.Name); err == nil && stat.IsDir() {
+					watcher.watchDir(event.Name)
+				}
+			}
+
+			if path, err := filepath.Rel(watcher.path, event.Name); err == nil {
+				watcher.changedPaths[filepath.ToSlash(path)] = true
+			}
+
+			debounce = time.After(watchDebounce)
+
+		case err, isOpen := <-watcher.fsWatcher.Errors:
+			if !isOpen {
+				return
+			}
+
+			watcher.engine.events.PublishError(watcher.context.ID, err)
+
+		case <-debounce:
+			// changes made during a reindex are picked up by the next one
+			if !watcher.reindex() {
+				debounce = time.After(watchDebounce)
+				continue
+			}
+
+			debounce = nil
+		}
+	}
+}
+
+// reindex starts a job reindexing the paths changed since the latest reindex,
+// unless that one is still running; a source no longer watched is left alone
+func (watcher *sourceWatcher) reindex() bool {
+	watcher.engine.watchersMutex.Lock()
+	defer watcher.engine.watchersMutex.Unlock()
+
+	if watcher.engine.watchers[watcher.source.ID] != watcher {
+		return true
+	}
+
+	if watcher.job != nil && !watcher.job.IsFinished() {
+		return false
+	}
+
+	changedPaths := make([]string, 0, len(watcher.changedPaths))
+	for path := range watcher.changedPaths {
+		changedPaths = append(changedPaths, path)
+	}
+
+	sort.Strings(changedPaths)
+	watcher.changedPaths = map[string]bool{}
+
+	// a changed directory is crawled along with its changed files
+	paths := make([]string, 0, len(changedPaths))
+	for _, path := range changedPaths {
+		if !isUnderPaths(path, paths) {
+			paths = append(paths, path)
+		}
+	}
+
+	watcher.job = watcher.engine.runJob(watcher.context, watcher.source.CanonicalURI, func(job *Job) (*Source, error) {
+		return watcher.context.reindexPaths(watcher.source.ID, paths, job)
+	})
+
+	return true
+}
+
+// catchUp reindexes the whole source, and waits for it to be done
+func (watcher *sourceWatcher) catchUp() {
+	watcher.engine.watchersMutex.Lock()
+
+	if watcher.engine.watchers[watcher.source.ID] != watcher {
+		watcher.engine.watchersMutex.Unlock()
+		return
+	}
+
+	job := watcher.engine.startJob(watcher.context, watcher.source.CanonicalURI)
+	watcher.job = job
+
+	watcher.engine.watchersMutex.Unlock()
+
+	job.wait()
+}
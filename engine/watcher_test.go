@@ -0,0 +1,104 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForWatcherJob waits for the watcher of a source to have run a reindex
+// through
+func waitForWatcherJob(t *testing.T, engine *Engine, source *Source) (job *Job) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * watchDebounce)
+
+	for time.Now().Before(deadline) {
+		engine.watchersMutex.Lock()
+		if watcher, isWatched := engine.watchers[source.ID]; isWatched {
+			job = watcher.job
+		}
+		engine.watchersMutex.Unlock()
+
+		if job != nil {
+			job.wait()
+			return
+		}
+
+		time.Sleep(watchDebounce / 10)
+	}
+
+	t.Fatalf("source %s was not reindexed", source.ID)
+	return
+}
+
+// TestWatchSource changes a watched source in a burst: a file is written, a
+// directory created with a file in it, and another file deleted; a single
+// reindex of the changed paths follows, crawling the new directory once
+func TestWatchSource(t *testing.T) {
+	engine, defaultContext := newTestEngine(t)
+
+	uri := newTestSourceDirOf(t, map[string]string{
+		"a.txt": "alpha",
+		"b.txt": "bravo",
+	})
+	dir := strings.TrimPrefix(uri, "file://")
+
+	_, source := indexTestSource(t, engine, defaultContext, uri)
+
+	if err := os.WriteFile(filepath.Join(dir, "c.txt"), []byte("charlie"), 0644); err != nil {
+		t.Fatalf("writing a file: %+v", err)
+	}
+
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatalf("creating a directory: %+v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "sub", "d.txt"), []byte("delta"), 0644); err != nil {
+		t.Fatalf("writing a file: %+v", err)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "a.txt")); err != nil {
+		t.Fatalf("removing a file: %+v", err)
+	}
+
+	job := waitForWatcherJob(t, engine, source)
+
+	assertJobCounts(t, job, 2, 0, 0, 1)
+	assertResourcesTotal(t, defaultContext, 3)
+
+	if source, err := defaultContext.GetSource(source.ID); err != nil {
+		t.Fatalf("getting the source: %+v", err)
+	} else if source.Stats.BytesIndexed != 17 {
+		t.Errorf("%d bytes indexed, want 17", source.Stats.BytesIndexed)
+	}
+}
+
+// TestWatcherReindexNoOverlap holds the reindexes of a watched source back
+// while the latest one is still running
+func TestWatcherReindexNoOverlap(t *testing.T) {
+	engine, defaultContext := newTestEngine(t)
+
+	_, source := indexTestSource(t, engine, defaultContext, newTestSourceDirOf(t, map[string]string{
+		"a.txt": "alpha",
+	}))
+
+	engine.watchersMutex.Lock()
+	watcher := engine.watchers[source.ID]
+	runningJob := NewJob(defaultContext.ID, source.CanonicalURI, engine.events)
+	watcher.job = runningJob
+	engine.watchersMutex.Unlock()
+
+	if watcher.reindex() {
+		t.Errorf("reindexed while the latest reindex is running")
+	}
+
+	engine.watchersMutex.Lock()
+	defer engine.watchersMutex.Unlock()
+
+	if watcher.job != runningJob {
+		t.Errorf("the running reindex was replaced")
+	}
+}
@@ -44,7 +44,7 @@ export const useIndexURIModal = (defaultProps: Partial<IndexURIModalProps> = {})
 
 export interface IndexURIModalProps {
     isOpen?: boolean
-    onSave?(source: api.protocol.Source)
+    onSave?()
     onClose?()
 }
 
@@ -71,7 +71,7 @@ export const IndexURIModal = ({ isOpen, onSave, onClose }: IndexURIModalProps) =
             }
 
             if (typeof onSave === 'function') {
-                onSave(response.source)
+                onSave()
             }
         } catch (err) {
             console.error(err)
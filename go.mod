@@ -4,6 +4,7 @@ go 1.17
 
 require (
 	github.com/blevesearch/bleve/v2 v2.3.3
+	github.com/fsnotify/fsnotify v1.5.4
 	github.com/necessitates/clover v1.3.0
 	github.com/sevlyar/go-daemon v0.1.6
 	github.com/urfave/cli/v2 v2.11.0
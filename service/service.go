@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"syscall"
 
 	"github.com/sevlyar/go-daemon"
@@ -12,17 +13,6 @@ import (
 	"risp/engine"
 )
 
-func handleSigTerm(sig os.Signal) (err error) {
-	log.Printf("Should stop now\n")
-
-	// stop <- struct{}{}
-	// if sig == syscall.SIGQUIT {
-	// 	<-done
-	// }
-
-	return daemon.ErrStop
-}
-
 type Service struct {
 	Config        *config.Config
 	DaemonContext *daemon.Context
@@ -42,6 +32,19 @@ func (service *Service) Run() (err error) {
 	service.Engine = engine.NewEngine(service.Config)
 
 	if service.NoExit {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		defer signal.Stop(signals)
+
+		go func() {
+			sig := <-signals
+			log.Printf("Received %s, stopping\n", sig)
+
+			if err := service.Engine.Stop(); err != nil {
+				log.Printf("failed to stop engine: %+v\n", err)
+			}
+		}()
+
 		if err = service.Engine.Start(); err != nil {
 			return
 		}
@@ -49,7 +52,8 @@ func (service *Service) Run() (err error) {
 		return
 	}
 
-	daemon.AddCommand(daemon.StringFlag(&service.Signal, "stop"), syscall.SIGTERM, handleSigTerm)
+	daemon.AddCommand(daemon.StringFlag(&service.Signal, "stop"), syscall.SIGTERM, service.handleSigTerm)
+	daemon.AddCommand(nil, syscall.SIGINT, service.handleSigTerm)
 
 	var daemonProcess *os.Process
 
@@ -77,6 +81,16 @@ func (service *Service) Run() (err error) {
 	return service.start()
 }
 
+func (service *Service) handleSigTerm(sig os.Signal) (err error) {
+	log.Printf("Received %s, stopping\n", sig)
+
+	if err := service.Engine.Stop(); err != nil {
+		log.Printf("failed to stop engine: %+v\n", err)
+	}
+
+	return daemon.ErrStop
+}
+
 func (service *Service) start() (err error) {
 	defer func() {
 		if err := service.stop(); err != nil {
@@ -84,9 +98,11 @@ func (service *Service) start() (err error) {
 		}
 	}()
 
-	if err = service.Engine.Start(); err != nil {
-		log.Fatal(err)
-	}
+	go func() {
+		if err := service.Engine.Start(); err != nil {
+			log.Fatal(err)
+		}
+	}()
 
 	fmt.Printf("About to serve signals\n")
 